@@ -2,11 +2,12 @@ package main
 
 import (
 	"bufio"
-	"encoding/binary"
 	"fmt"
 	"log"
 	"math"
 	"os"
+
+	"github.com/koji-ohki-1974/char2vec"
 )
 
 const max_size int = 2000 // max length of strings
@@ -16,45 +17,68 @@ const max_w int = 50      // max length of vocabulary entries
 func main() {
 	args := os.Args
 	var st1 string
-	var bestw []rune = make([]rune, N)
+	var bestw []string = make([]string, N)
 	var dist, length float64
 	var bestd []float64 = make([]float64, N)
 	var vec []float64 = make([]float64, max_size)
 	var chars, size, a, b, c, d, cn int
 	var bi []int = make([]int, 100)
+	format := char2vec.FormatAuto
+	context_file := ""
+	comp_file, table_file := "", ""
+	normalize_form := ""
+	for len(args) > 2 {
+		if args[1] == "-text" {
+			format = char2vec.FormatText
+			args = args[1:]
+		} else if args[1] == "-context" && len(args) > 3 {
+			context_file = args[2]
+			args = args[2:]
+		} else if args[1] == "-component-vectors" && len(args) > 3 {
+			comp_file = args[2]
+			args = args[2:]
+		} else if args[1] == "-components" && len(args) > 3 {
+			table_file = args[2]
+			args = args[2:]
+		} else if args[1] == "-normalize" && len(args) > 3 {
+			normalize_form = args[2]
+			args = args[2:]
+		} else {
+			break
+		}
+	}
 	if len(args) < 2 {
-		fmt.Fprintf(os.Stderr, "Usage: ./char-distance <FILE>\nwhere FILE contains character projections in the BINARY FORMAT\n")
+		fmt.Fprintf(os.Stderr, "Usage: ./char-distance [-text] [-context <CFILE>] [-component-vectors <VFILE> [-components <TFILE>]] [-normalize <FORM>] <FILE>\nwhere FILE contains character projections in the text or binary format\n")
+		fmt.Fprintf(os.Stderr, "The format is detected from FILE; -text reads it in the text format\n")
+		fmt.Fprintf(os.Stderr, "With -context, candidates are compared by their context vectors from CFILE (written by -output-context)\n")
+		fmt.Fprintf(os.Stderr, "With -component-vectors, characters missing from FILE get the average of the vectors of their components\n")
+		fmt.Fprintf(os.Stderr, "from VFILE (written by -output-components): their entries of TFILE, or their canonical decompositions\n")
+		fmt.Fprintf(os.Stderr, "With -normalize, queries are normalized to FORM (nfc, nfkc or nfkc_casefold) when FILE does not record\n")
+		fmt.Fprintf(os.Stderr, "its normalization; it is an error when FILE records another one\n")
 		os.Exit(0)
 	}
 	file_name := args[1]
-	f, err := os.Open(file_name)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Input file not found\n")
-		os.Exit(-1)
-	}
-	defer f.Close()
-	br := bufio.NewReader(f)
-	fmt.Fscanf(br, "%d", &chars)
+	model, err := char2vec.LoadFormat(file_name, format)
+	failOnError(err, "Cannot read input file")
+	chars = len(model.Vocab)
 	fmt.Fprintf(os.Stderr, "characters: %d\n", chars)
-	fmt.Fscanf(br, "%d", &size)
+	size = model.Size
 	fmt.Fprintf(os.Stderr, "size: %d\n", size)
-	br.ReadByte()
-	vocab := make([]rune, chars)
-	M := make([]float64, chars*size)
-	for b = 0; b < chars; b++ {
-		vocab[b], _, err = br.ReadRune()
-		br.ReadByte()
-		err = binary.Read(br, binary.LittleEndian, M[b*size:b*size+size])
-		failOnError(err, "Cannot read input file")
-		length = 0
-		for a = 0; a < size; a++ {
-			length += M[a+b*size] * M[a+b*size]
-		}
-		length = math.Sqrt(length)
-		for a = 0; a < size; a++ {
-			M[a+b*size] /= length
-		}
-		br.ReadByte()
+	if normalize_form != "" {
+		failOnError(model.SetNormalization(normalize_form), "Cannot normalize queries")
+	}
+	if model.Normalization != "" {
+		fmt.Fprintf(os.Stderr, "normalize: %s\n", model.Normalization)
+	}
+	vocab := model.Vocab
+	M := model.Normalized
+	C := M // vectors of the candidate characters
+	if context_file != "" {
+		failOnError(model.LoadContext(context_file), "Cannot read context file")
+		C = model.NormalizedContext
+	}
+	if comp_file != "" {
+		failOnError(model.LoadComponents(comp_file, table_file), "Cannot read component vectors file")
 	}
 	scanner := bufio.NewScanner(os.Stdin)
 	for {
@@ -62,7 +86,7 @@ func main() {
 			bestd[a] = 0
 		}
 		for a = 0; a < N; a++ {
-			bestw[a] = 0
+			bestw[a] = ""
 		}
 		fmt.Printf("Enter character or character sequence (EXIT to break): ")
 		sf := scanner.Scan()
@@ -73,20 +97,19 @@ func main() {
 		if st1 == "EXIT" {
 			break
 		}
+		st1 = model.NormalizeText(st1)
 		a = 0
 		b = 0
 		c = 0
-		for _, ch := range st1 {
-			for b = 0; b < chars; b++ {
-				if vocab[b] == ch {
-					break
-				}
-			}
-			if b == chars {
-				b = -1
-			}
+		tokens := model.Tokens(st1)
+		if model.Index(st1) != -1 {
+			// The whole sequence is an n-gram of the vocabulary
+			tokens = []string{st1}
+		}
+		for _, ch := range tokens {
+			b = model.Compose(ch)
 			bi[a] = b
-			fmt.Printf("\nCharacter: %c  Position in vocabulary: %d\n", ch, bi[a])
+			fmt.Printf("\nCharacter: %s  Position in vocabulary: %d\n", ch, bi[a])
 			if b == -1 {
 				fmt.Printf("Out of dictionary character!\n")
 				break
@@ -94,6 +117,13 @@ func main() {
 			a++
 		}
 		cn = a
+		// Compose may have added characters to the vocabulary
+		vocab, M, chars = model.Vocab, model.Normalized, len(model.Vocab)
+		if context_file != "" {
+			C = model.NormalizedContext
+		} else {
+			C = M
+		}
 		if b == -1 {
 			continue
 		}
@@ -121,7 +151,7 @@ func main() {
 			bestd[a] = -1
 		}
 		for a = 0; a < N; a++ {
-			bestw[a] = 0
+			bestw[a] = ""
 		}
 		for c = 0; c < chars; c++ {
 			a = 0
@@ -135,7 +165,7 @@ func main() {
 			}
 			dist = 0
 			for a = 0; a < size; a++ {
-				dist += vec[a] * M[a+c*size]
+				dist += vec[a] * C[a+c*size]
 			}
 			for a = 0; a < N; a++ {
 				if dist > bestd[a] {
@@ -150,7 +180,7 @@ func main() {
 			}
 		}
 		for a = 0; a < N; a++ {
-			fmt.Printf("%10c\t\t%f\n", bestw[a], bestd[a])
+			fmt.Printf("%10s\t\t%f\n", bestw[a], bestd[a])
 		}
 	}
 	os.Exit(0)
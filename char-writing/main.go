@@ -2,12 +2,13 @@ package main
 
 import (
 	"bufio"
-	"encoding/binary"
 	"fmt"
 	"log"
 	"math"
 	"math/rand"
 	"os"
+
+	"github.com/koji-ohki-1974/char2vec"
 )
 
 const max_size int = 2000 // max length of strings
@@ -24,7 +25,7 @@ func main() {
 	args := os.Args
 	var st1 string
 	var besti []int = make([]int, N)
-	var bestw []rune = make([]rune, N)
+	var bestw []string = make([]string, N)
 	var dist, length float64
 	var bestd []float64 = make([]float64, N)
 	var vec []float64 = make([]float64, max_size)
@@ -32,39 +33,62 @@ func main() {
 	var bi0 []int
 	var bi []int
 
+	format := char2vec.FormatAuto
+	context_file := ""
+	comp_file, table_file := "", ""
+	normalize_form := ""
+	for len(args) > 2 {
+		if args[1] == "-text" {
+			format = char2vec.FormatText
+			args = args[1:]
+		} else if args[1] == "-context" && len(args) > 3 {
+			context_file = args[2]
+			args = args[2:]
+		} else if args[1] == "-component-vectors" && len(args) > 3 {
+			comp_file = args[2]
+			args = args[2:]
+		} else if args[1] == "-components" && len(args) > 3 {
+			table_file = args[2]
+			args = args[2:]
+		} else if args[1] == "-normalize" && len(args) > 3 {
+			normalize_form = args[2]
+			args = args[2:]
+		} else {
+			break
+		}
+	}
 	if len(args) < 2 {
-		fmt.Fprintf(os.Stderr, "Usage: ./char-writing <FILE>\nwhere FILE contains character projections in the BINARY FORMAT\n")
+		fmt.Fprintf(os.Stderr, "Usage: ./char-writing [-text] [-context <CFILE>] [-component-vectors <VFILE> [-components <TFILE>]] [-normalize <FORM>] <FILE>\nwhere FILE contains character projections in the text or binary format\n")
+		fmt.Fprintf(os.Stderr, "The format is detected from FILE; -text reads it in the text format\n")
+		fmt.Fprintf(os.Stderr, "With -context, candidates are compared by their context vectors from CFILE (written by -output-context)\n")
+		fmt.Fprintf(os.Stderr, "With -component-vectors, characters missing from FILE get the average of the vectors of their components\n")
+		fmt.Fprintf(os.Stderr, "from VFILE (written by -output-components): their entries of TFILE, or their canonical decompositions\n")
+		fmt.Fprintf(os.Stderr, "With -normalize, queries are normalized to FORM (nfc, nfkc or nfkc_casefold) when FILE does not record\n")
+		fmt.Fprintf(os.Stderr, "its normalization; it is an error when FILE records another one\n")
 		os.Exit(0)
 	}
 	file_name := args[1]
-	f, err := os.Open(file_name)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Input file not found\n")
-		os.Exit(-1)
-	}
-	defer f.Close()
-	br := bufio.NewReader(f)
-	fmt.Fscanf(br, "%d", &chars)
+	model, err := char2vec.LoadFormat(file_name, format)
+	failOnError(err, "Cannot read input file")
+	chars = len(model.Vocab)
 	fmt.Fprintf(os.Stderr, "characters: %d\n", chars)
-	fmt.Fscanf(br, "%d", &size)
+	size = model.Size
 	fmt.Fprintf(os.Stderr, "size: %d\n", size)
-	br.ReadByte()
-	vocab := make([]rune, chars)
-	M := make([]float64, chars*size)
-	for b = 0; b < chars; b++ {
-		vocab[b], _, err = br.ReadRune()
-		br.ReadByte()
-		err = binary.Read(br, binary.LittleEndian, M[b*size:b*size+size])
-		failOnError(err, "Cannot read input file")
-		length = 0
-		for a = 0; a < size; a++ {
-			length += M[a+b*size] * M[a+b*size]
-		}
-		length = math.Sqrt(length)
-		for a = 0; a < size; a++ {
-			M[a+b*size] /= length
-		}
-		br.ReadByte()
+	if normalize_form != "" {
+		failOnError(model.SetNormalization(normalize_form), "Cannot normalize queries")
+	}
+	if model.Normalization != "" {
+		fmt.Fprintf(os.Stderr, "normalize: %s\n", model.Normalization)
+	}
+	vocab := model.Vocab
+	M := model.Normalized
+	C := M // vectors of the candidate characters
+	if context_file != "" {
+		failOnError(model.LoadContext(context_file), "Cannot read context file")
+		C = model.NormalizedContext
+	}
+	if comp_file != "" {
+		failOnError(model.LoadComponents(comp_file, table_file), "Cannot read component vectors file")
 	}
 	scanner := bufio.NewScanner(os.Stdin)
 	for {
@@ -77,25 +101,26 @@ func main() {
 		if st1 == "EXIT" {
 			break
 		}
+		st1 = model.NormalizeText(st1)
 		a = 0
 		b = 0
 		c = 0
 		n := 0
 		bi0 = []int{}
 		bi = make([]int, window)
-		for _, ch := range st1 {
-			for b = 0; b < chars; b++ {
-				if vocab[b] == ch {
-					break
-				}
-			}
-			if b == chars {
-				b = -1
-			}
+		for _, ch := range model.Tokens(st1) {
+			b = model.Compose(ch)
 			bi0 = append(bi0, b)
-			fmt.Printf("%c", ch)
+			fmt.Printf("%s", ch)
 			n++
 		}
+		// Compose may have added characters to the vocabulary
+		vocab, M, chars = model.Vocab, model.Normalized, len(model.Vocab)
+		if context_file != "" {
+			C = model.NormalizedContext
+		} else {
+			C = M
+		}
 
 		bilen := 0
 		bipos := 0
@@ -108,7 +133,7 @@ func main() {
 				bestd[a] = -1
 			}
 			for a = 0; a < N; a++ {
-				bestw[a] = 0
+				bestw[a] = ""
 			}
 			for a = 0; a < size; a++ {
 				vec[a] = 0
@@ -136,7 +161,7 @@ func main() {
 			for c = 0; c < chars; c++ {
 				dist = 0
 				for a = 0; a < size; a++ {
-					dist += vec[a] * M[a+c*size]
+					dist += vec[a] * C[a+c*size]
 				}
 				for a = 0; a < N; a++ {
 					if dist > bestd[a] {
@@ -166,7 +191,7 @@ func main() {
 					sum += bestd[a]
 				}
 				if r < sum {
-					fmt.Printf("%c", bestw[a])
+					fmt.Printf("%s", bestw[a])
 					bi[bipos] = besti[a]
 					lasti = besti[a]
 					bipos = (bipos + 1) % window
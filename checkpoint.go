@@ -0,0 +1,294 @@
+package char2vec
+
+import (
+	"bufio"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+const checkpoint_version int = 1
+
+// threadState is the position of a training goroutine, recorded between
+// two sentences. A LocalIter of 0 means the goroutine has finished.
+type threadState struct {
+	File          int   // index of the part being read among those of the goroutine
+	Pos           int64 // offset of the next character in the file
+	LocalIter     int
+	CharCount     int64
+	LastCharCount int64
+	NextRandom    uint64
+}
+
+// checkpoint is the training state written by saveCheckpoint.
+type checkpoint struct {
+	Version         int
+	TrainFiles      []string
+	FileSizes       []int64
+	Size            int
+	Window          int
+	Sample          float64
+	HS              int
+	Negative        int
+	Threads         int
+	Iter            int
+	CBOW            int
+	SentenceBreak   string
+	Normalize       string
+	Unit            string
+	NGram           []int
+	NGramMode       string
+	StartingAlpha   float64
+	Alpha           float64
+	TrainChars      int64
+	CharCountActual int64
+	Tokens          []string
+	Counts          []int64
+	Syn0            []float32
+	Syn1            []float32
+	Syn1neg         []float32
+	Frozen          []bool
+	ComponentsFile  string
+	Decompose       int
+	CompTable       map[string][]string
+	CompNames       []string
+	Comps           [][]int
+	Syn0comp        []float32
+	Rare            []string
+	ThreadStates    []threadState
+	Seed            int64
+	Deterministic   int
+	Turn            int
+}
+
+// decodeCheckpoint reads a checkpoint.
+func decodeCheckpoint(r io.Reader) (*checkpoint, error) {
+	var cp checkpoint
+	if err := gob.NewDecoder(r).Decode(&cp); err != nil {
+		return nil, err
+	}
+	if cp.Version != checkpoint_version {
+		return nil, fmt.Errorf("unsupported checkpoint version %d", cp.Version)
+	}
+	return &cp, nil
+}
+
+// checkpointTick returns how often Train checks whether a checkpoint is due.
+func (t *Trainer) checkpointTick() time.Duration {
+	if t.cfg.CheckpointChars > 0 || t.cfg.CheckpointEvery <= 0 {
+		return time.Second
+	}
+	return t.cfg.CheckpointEvery
+}
+
+// sentenceBoundary is called by the training goroutines between two
+// sentences, after recording their position. It returns the context's
+// error when training is cancelled, and waits while a checkpoint is
+// being written.
+func (t *Trainer) sentenceBoundary(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+	if atomic.LoadInt32(&t.pausing) == 0 {
+		return nil
+	}
+	t.pause_mu.Lock()
+	t.paused++
+	t.pause_cond.Broadcast()
+	for atomic.LoadInt32(&t.pausing) != 0 {
+		t.pause_cond.Wait()
+	}
+	t.paused--
+	t.pause_mu.Unlock()
+	return nil
+}
+
+// threadDone is called when a training goroutine returns with err.
+func (t *Trainer) threadDone(id int, err error) {
+	t.pause_mu.Lock()
+	t.running--
+	t.finished[id] = true
+	if t.turn == id && err != context.Canceled {
+		t.nextTurn()
+	}
+	t.pause_cond.Broadcast()
+	t.pause_mu.Unlock()
+}
+
+// saveCheckpoint waits until every training goroutine reaches the end of its
+// current sentence and writes the training state to CheckpointFile.
+func (t *Trainer) saveCheckpoint() error {
+	t.pause_mu.Lock()
+	defer t.pause_mu.Unlock()
+	atomic.StoreInt32(&t.pausing, 1)
+	for t.paused < t.running {
+		t.pause_cond.Wait()
+	}
+	err := t.writeCheckpoint(t.cfg.CheckpointFile)
+	atomic.StoreInt32(&t.pausing, 0)
+	t.pause_cond.Broadcast()
+	return err
+}
+
+// writeCheckpoint writes the training state to file. The training
+// goroutines must not be running; use saveCheckpoint during training.
+func (t *Trainer) writeCheckpoint(file string) error {
+	if t.cfg.Debug > 0 {
+		fmt.Fprintf(t.log, "\nWriting checkpoint %s\n", file)
+	}
+	cp := checkpoint{
+		Version:         checkpoint_version,
+		TrainFiles:      t.files,
+		FileSizes:       t.file_sizes,
+		Size:            t.cfg.Size,
+		Window:          t.cfg.Window,
+		Sample:          t.cfg.Sample,
+		HS:              t.cfg.HS,
+		Negative:        t.cfg.Negative,
+		Threads:         t.cfg.Threads,
+		Iter:            t.cfg.Iter,
+		CBOW:            t.cfg.CBOW,
+		SentenceBreak:   t.cfg.SentenceBreak,
+		Normalize:       t.cfg.Normalize,
+		Unit:            t.cfg.Unit,
+		NGram:           t.cfg.NGram,
+		NGramMode:       t.cfg.NGramMode,
+		StartingAlpha:   t.sched.starting_alpha,
+		Alpha:           t.sched.alpha(),
+		TrainChars:      t.train_chars,
+		CharCountActual: t.sched.progress(),
+		Tokens:          make([]string, t.vocab_size),
+		Counts:          make([]int64, t.vocab_size),
+		Syn0:            t.syn0,
+		Syn1:            t.syn1,
+		Syn1neg:         t.syn1neg,
+		Frozen:          t.frozen,
+		ComponentsFile:  t.cfg.ComponentsFile,
+		Decompose:       t.cfg.Decompose,
+		CompTable:       t.comp_table,
+		CompNames:       t.comp_names,
+		Comps:           t.comps,
+		Syn0comp:        t.syn0comp,
+		Rare:            t.rare,
+		ThreadStates:    t.threads,
+		Seed:            t.cfg.Seed,
+		Deterministic:   t.cfg.Deterministic,
+		Turn:            t.turn,
+	}
+	for a := 0; a < t.vocab_size; a++ {
+		cp.Tokens[a] = t.vocab[a].char
+		cp.Counts[a] = t.vocab[a].cn
+	}
+	// Write to a temporary file first, so that a crash while writing
+	// leaves the previous checkpoint intact
+	f, err := os.Create(file + ".tmp")
+	if err != nil {
+		return err
+	}
+	fo := bufio.NewWriter(f)
+	err = gob.NewEncoder(fo).Encode(&cp)
+	if err == nil {
+		err = fo.Flush()
+	}
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		os.Remove(file + ".tmp")
+		return err
+	}
+	return os.Rename(file+".tmp", file)
+}
+
+// readCheckpoint restores the training state from ResumeFile. The
+// training parameters recorded in the checkpoint replace those of the
+// configuration, so that the learning rate schedule continues unchanged.
+func (t *Trainer) readCheckpoint() error {
+	fmt.Fprintln(t.log, "ReadCheckpoint")
+	f, err := os.Open(t.cfg.ResumeFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	cp, err := decodeCheckpoint(bufio.NewReader(f))
+	if err != nil {
+		return fmt.Errorf("%s: %v", t.cfg.ResumeFile, err)
+	}
+	t.files = cp.TrainFiles
+	if t.cfg.TrainFile != "" || t.cfg.TrainListFile != "" {
+		// The training files may have been moved since the checkpoint
+		if err := t.initTrainFiles(); err != nil {
+			return err
+		}
+	}
+	if len(t.files) != len(cp.FileSizes) {
+		return fmt.Errorf("%s: %d training files instead of %d", t.cfg.ResumeFile, len(t.files), len(cp.FileSizes))
+	}
+	for a, file := range t.files {
+		fi, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		if fi.Size() != cp.FileSizes[a] {
+			return fmt.Errorf("%s: training file %s has changed since the checkpoint", t.cfg.ResumeFile, file)
+		}
+	}
+	t.file_sizes = cp.FileSizes
+	t.cfg.Size = cp.Size
+	t.cfg.Window = cp.Window
+	t.cfg.Sample = cp.Sample
+	t.cfg.HS = cp.HS
+	t.cfg.Negative = cp.Negative
+	t.cfg.Threads = cp.Threads
+	t.cfg.Iter = cp.Iter
+	t.cfg.CBOW = cp.CBOW
+	t.cfg.SentenceBreak = cp.SentenceBreak
+	t.sentence_break = sentenceBreaks(cp.SentenceBreak)
+	t.cfg.Normalize = cp.Normalize
+	t.cfg.Unit = cp.Unit
+	t.cfg.NGram = cp.NGram
+	t.cfg.NGramMode = cp.NGramMode
+	t.train_chars = cp.TrainChars
+	t.sched = newSchedule(cp.StartingAlpha, int64(cp.Iter)*cp.TrainChars)
+	t.sched.restore(cp.CharCountActual, cp.Alpha)
+	t.vocab_size = len(cp.Tokens)
+	t.vocab = make(vocab_slice, t.vocab_size+1)
+	t.vocab_hash = map[string]int{}
+	for a := 0; a < t.vocab_size; a++ {
+		t.vocab[a].char = cp.Tokens[a]
+		t.vocab[a].cn = cp.Counts[a]
+		t.vocab[a].code = make([]byte, MAX_CODE_LENGTH)
+		t.vocab[a].point = make([]int, MAX_CODE_LENGTH)
+		t.vocab_hash[cp.Tokens[a]] = a
+	}
+	t.createBinaryTree()
+	t.syn0 = cp.Syn0
+	t.syn1 = cp.Syn1
+	t.syn1neg = cp.Syn1neg
+	t.frozen = cp.Frozen
+	t.cfg.ComponentsFile = cp.ComponentsFile
+	t.cfg.Decompose = cp.Decompose
+	t.comp_table = cp.CompTable
+	t.comp_names = cp.CompNames
+	t.comps = cp.Comps
+	t.syn0comp = cp.Syn0comp
+	t.rare = cp.Rare
+	t.threads = cp.ThreadStates
+	t.cfg.Seed = cp.Seed
+	t.cfg.Deterministic = cp.Deterministic
+	t.turn = cp.Turn
+	if len(t.syn0) != t.vocab_size*t.cfg.Size || len(t.threads) != t.cfg.Threads {
+		return fmt.Errorf("%s: inconsistent checkpoint", t.cfg.ResumeFile)
+	}
+	if t.cfg.Debug > 0 {
+		fmt.Fprintf(t.log, "Vocab size: %d\n", t.vocab_size)
+		fmt.Fprintf(t.log, "Resuming at %d of %d characters\n", t.sched.progress(), t.sched.total)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package char2vec
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// writeTestCorpus writes lines of pseudo-random characters, the more
+// frequent the lower their code.
+func writeTestCorpus(t *testing.T, lines int) string {
+	var sb strings.Builder
+	var next_random uint64 = 1
+	for a := 0; a < lines; a++ {
+		for b := 0; b < 60; b++ {
+			next_random = next_random*25214903917 + 11
+			r := next_random >> 16 % 1024
+			sb.WriteRune('あ' + rune(r*r/1024/16))
+		}
+		sb.WriteString("\n")
+	}
+	path := filepath.Join(t.TempDir(), "corpus.txt")
+	if err := os.WriteFile(path, []byte(sb.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// testConfig returns the configuration of a small reproducible training.
+func testConfig(train string) Config {
+	cfg := DefaultConfig()
+	cfg.TrainFile = train
+	cfg.Size = 10
+	cfg.Threads = 3
+	cfg.Iter = 2
+	cfg.MinCount = 1
+	cfg.Debug = 0
+	cfg.Log = io.Discard
+	cfg.Seed = 1
+	cfg.Deterministic = 1
+	return cfg
+}
+
+// newTestTrainer returns a trainer with a small unigram table.
+func newTestTrainer(cfg Config) *Trainer {
+	tr := NewTrainer(cfg)
+	tr.unigram_size = 1e5
+	return tr
+}
+
+// cancelWriter cancels the training on the first progress line.
+type cancelWriter struct {
+	cancel context.CancelFunc
+	once   sync.Once
+}
+
+func (w *cancelWriter) Write(p []byte) (int, error) {
+	if strings.Contains(string(p), "Alpha") {
+		w.once.Do(w.cancel)
+	}
+	return len(p), nil
+}
+
+func sameVectors(t *testing.T, name string, got, want []float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: %d values, want %d", name, len(got), len(want))
+	}
+	for a := range want {
+		if got[a] != want[a] {
+			t.Fatalf("%s: value %d is %g, want %g", name, a, got[a], want[a])
+		}
+	}
+}
+
+func TestCheckpointResume(t *testing.T) {
+	cfg := testConfig(writeTestCorpus(t, 1000))
+	full, err := newTestTrainer(cfg).Train(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Interrupt a training at its first progress report
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupted := cfg
+	interrupted.CheckpointFile = filepath.Join(t.TempDir(), "checkpoint")
+	interrupted.Debug = 2
+	interrupted.Log = &cancelWriter{cancel: cancel}
+	m, err := newTestTrainer(interrupted).Train(ctx)
+	if err != context.Canceled || m == nil || !m.Partial {
+		t.Fatalf("interrupted training returned %v", err)
+	}
+	// Resuming it trains the same vectors as the full training
+	resumed := DefaultConfig()
+	resumed.ResumeFile = interrupted.CheckpointFile
+	resumed.Debug = 0
+	resumed.Log = io.Discard
+	m, err = newTestTrainer(resumed).Train(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(m.Vocab, "") != strings.Join(full.Vocab, "") {
+		t.Fatal("resumed training has another vocabulary")
+	}
+	sameVectors(t, "vectors", m.Vectors, full.Vectors)
+	sameVectors(t, "context", m.Context, full.Context)
+}
@@ -0,0 +1,268 @@
+package char2vec
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// readComponentTable reads a component table of "character component..."
+// lines, separated by whitespace. Tokens may be escaped as in vocabulary
+// files; lines starting with # are ignored.
+func readComponentTable(file string) (map[string][]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	table := map[string][]string{}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		tokens := make([]string, len(fields))
+		for a, field := range fields {
+			token, ok := unescapeToken(field)
+			if !ok {
+				return nil, fmt.Errorf("%s:%d: invalid character %q", file, line, field)
+			}
+			tokens[a] = token
+		}
+		table[tokens[0]] = tokens[1:]
+	}
+	return table, scanner.Err()
+}
+
+// componentsOf returns the components of a character: its entry of the
+// component table, or its canonical decomposition when Decompose is set.
+func (t *Trainer) componentsOf(char string) []string {
+	return componentsOf(t.comp_table, t.cfg.Decompose != 0 && !t.isNGram(char), char)
+}
+
+func componentsOf(table map[string][]string, decompose bool, char string) []string {
+	if comps, ok := table[char]; ok {
+		return comps
+	}
+	if decompose {
+		if d := norm.NFD.String(char); d != char {
+			return Tokens(UnitChar, d)
+		}
+	}
+	return nil
+}
+
+// initComponents allocates the vectors of the components of the
+// vocabulary. With components, the input vector of a character is the
+// average of its own vector and the vectors of its components, as in
+// fastText. Train calls it after initNet.
+func (t *Trainer) initComponents() error {
+	if t.cfg.ComponentsFile == "" && t.cfg.Decompose == 0 {
+		return nil
+	}
+	fmt.Fprintln(t.log, "InitComponents")
+	if err := t.indexComponents(); err != nil {
+		return err
+	}
+	layer1_size := t.cfg.Size
+	var next_random uint64 = seedRandom(t.cfg.Seed, 1)
+	t.syn0comp = make([]float32, len(t.comp_names)*layer1_size)
+	for a := range t.syn0comp {
+		next_random = next_random*uint64(25214903917) + 11
+		t.syn0comp[a] = float32(((float64(next_random&0xFFFF) / float64(65536)) - 0.5) / float64(layer1_size))
+	}
+	if t.cfg.Debug > 0 {
+		fmt.Fprintf(t.log, "Components: %d\n", len(t.comp_names))
+	}
+	return nil
+}
+
+// indexComponents reads ComponentsFile and numbers the components of the
+// vocabulary, without allocating their vectors.
+func (t *Trainer) indexComponents() error {
+	if t.cfg.ComponentsFile != "" {
+		table, err := readComponentTable(t.cfg.ComponentsFile)
+		if err != nil {
+			return err
+		}
+		t.comp_table = table
+	}
+	comp_hash := map[string]int{}
+	t.comp_names = nil
+	t.comps = make([][]int, t.vocab_size)
+	for a := 1; a < t.vocab_size; a++ {
+		for _, comp := range t.componentsOf(t.vocab[a].char) {
+			i, ok := comp_hash[comp]
+			if !ok {
+				i = len(t.comp_names)
+				comp_hash[comp] = i
+				t.comp_names = append(t.comp_names, comp)
+			}
+			t.comps[a] = append(t.comps[a], i)
+		}
+	}
+	return nil
+}
+
+// addInputVector adds the input vector of a character to vec.
+func (t *Trainer) addInputVector(vec []float64, char int) {
+	layer1_size := t.cfg.Size
+	comps := t.comps[char]
+	scale := 1 / float64(len(comps)+1)
+	l1 := char * layer1_size
+	for c := 0; c < layer1_size; c++ {
+		vec[c] += float64(t.syn0[c+l1]) * scale
+	}
+	for _, i := range comps {
+		l1 = i * layer1_size
+		for c := 0; c < layer1_size; c++ {
+			vec[c] += float64(t.syn0comp[c+l1]) * scale
+		}
+	}
+}
+
+// learnInput adds the error of the input vector of a character to its
+// own vector and to the vectors of its components, each in proportion.
+func (t *Trainer) learnInput(char int, neu1e []float64) {
+	layer1_size := t.cfg.Size
+	comps := t.comps[char]
+	scale := 1 / float64(len(comps)+1)
+	l1 := char * layer1_size
+	for c := 0; c < layer1_size; c++ {
+		t.syn0[c+l1] += float32(neu1e[c] * scale)
+	}
+	for _, i := range comps {
+		l1 = i * layer1_size
+		for c := 0; c < layer1_size; c++ {
+			t.syn0comp[c+l1] += float32(neu1e[c] * scale)
+		}
+	}
+}
+
+// componentVectors returns the input vectors of the vocabulary, and the
+// characters missing from it with the averages of their components: the
+// characters of the component table and those discarded by min_count.
+func (t *Trainer) componentVectors() ([]string, []float64) {
+	layer1_size := t.cfg.Size
+	vocab := make([]string, t.vocab_size)
+	vectors := make([]float64, t.vocab_size*layer1_size)
+	for a := 0; a < t.vocab_size; a++ {
+		vocab[a] = t.vocab[a].char
+		t.addInputVector(vectors[a*layer1_size:(a+1)*layer1_size], a)
+	}
+	comp_hash := make(map[string]int, len(t.comp_names))
+	for i, comp := range t.comp_names {
+		comp_hash[comp] = i
+	}
+	var missing []string
+	for char := range t.comp_table {
+		missing = append(missing, char)
+	}
+	sort.Strings(missing)
+	seen := map[string]bool{}
+	for _, char := range append(missing, t.rare...) {
+		if seen[char] || t.searchVocab(char) != -1 {
+			continue
+		}
+		seen[char] = true
+		vec := make([]float64, layer1_size)
+		n := 0
+		for _, comp := range t.componentsOf(char) {
+			i, ok := comp_hash[comp]
+			if !ok {
+				continue
+			}
+			for c := 0; c < layer1_size; c++ {
+				vec[c] += float64(t.syn0comp[c+i*layer1_size])
+			}
+			n++
+		}
+		if n == 0 {
+			continue
+		}
+		for c := 0; c < layer1_size; c++ {
+			vec[c] /= float64(n)
+		}
+		vocab = append(vocab, char)
+		vectors = append(vectors, vec...)
+	}
+	return vocab, vectors
+}
+
+// ComponentsModel returns a model whose vectors are the vectors of the
+// components of characters, or nil when training used no components.
+func (m *Model) ComponentsModel() *Model {
+	return m.components
+}
+
+// LoadComponents reads the vectors of the components of characters
+// written by the -output-components option of the char2vec command, and
+// the component table of its -components option unless table is "".
+// Compose then gives vectors to the characters missing from the
+// vocabulary. The components of a character are its entry of the table,
+// or its canonical decomposition, as with -decompose.
+func (m *Model) LoadComponents(path, table string) error {
+	c, err := Load(path)
+	if err != nil {
+		return err
+	}
+	if c.Size != m.Size {
+		return fmt.Errorf("%s: size %d does not match size %d", path, c.Size, m.Size)
+	}
+	m.components = c
+	m.comp_table = nil
+	if table != "" {
+		if m.comp_table, err = readComponentTable(table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Compose adds a character missing from the vocabulary with the average
+// of the vectors of its components, as the char2vec command writes the
+// characters discarded by min_count, and a zero context vector. It
+// returns the position of the character in the vocabulary, or -1 when
+// no component of the character has a vector. Vectors, Normalized and
+// Context are reallocated as they grow.
+func (m *Model) Compose(char string) int {
+	if i := m.Index(char); i != -1 {
+		return i
+	}
+	if m.components == nil {
+		return -1
+	}
+	size := m.Size
+	vec := make([]float64, size)
+	n := 0
+	for _, comp := range componentsOf(m.comp_table, true, char) {
+		i := m.components.Index(comp)
+		if i == -1 {
+			continue
+		}
+		for c := 0; c < size; c++ {
+			vec[c] += m.components.Vectors[c+i*size]
+		}
+		n++
+	}
+	if n == 0 {
+		return -1
+	}
+	for c := 0; c < size; c++ {
+		vec[c] /= float64(n)
+	}
+	m.Vocab = append(m.Vocab, char)
+	m.Vectors = append(m.Vectors, vec...)
+	m.Normalized = append(m.Normalized, normalize(vec, size)...)
+	if m.Context != nil {
+		m.Context = append(m.Context, make([]float64, size)...)
+		m.NormalizedContext = append(m.NormalizedContext, make([]float64, size)...)
+	}
+	m.index[char] = len(m.Vocab) - 1
+	return len(m.Vocab) - 1
+}
@@ -0,0 +1,43 @@
+package char2vec
+
+import "testing"
+
+func TestCompose(t *testing.T) {
+	m := newModel([]string{sentence_end, "a"}, 2, []float64{0, 0, 1, 0})
+	if m.Compose("é") != -1 {
+		t.Fatal("composed a character without component vectors")
+	}
+	m.components = newModel([]string{"e", "́", "x"}, 2, []float64{1, 0, 0, 1, 5, 5})
+	m.comp_table = map[string][]string{"ẍ": {"x", "?"}}
+	tests := []struct {
+		char string
+		want []float64 // nil when the character cannot be composed
+	}{
+		{"a", []float64{1, 0}},
+		{"é", []float64{0.5, 0.5}}, // canonical decomposition
+		{"ẍ", []float64{5, 5}},     // table entry, components without vectors skipped
+		{"ü", nil},
+		{"b", nil},
+	}
+	for _, test := range tests {
+		i := m.Compose(test.char)
+		if test.want == nil {
+			if i != -1 {
+				t.Errorf("Compose(%q) = %d, want -1", test.char, i)
+			}
+			continue
+		}
+		if i == -1 || m.Vocab[i] != test.char || m.Index(test.char) != i {
+			t.Fatalf("Compose(%q) = %d, not in the vocabulary", test.char, i)
+		}
+		for c, v := range test.want {
+			if m.Vectors[i*2+c] != v {
+				t.Errorf("Compose(%q): vector %v, want %v", test.char, m.Vectors[i*2:i*2+2], test.want)
+				break
+			}
+		}
+	}
+	if len(m.Normalized) != len(m.Vectors) {
+		t.Errorf("%d normalized values for %d values", len(m.Normalized), len(m.Vectors))
+	}
+}
@@ -0,0 +1,97 @@
+package char2vec
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+// Config holds the training parameters. The field comments name the
+// corresponding command line options of the char2vec command.
+type Config struct {
+	TrainFile     string  // -train: text data used to train the model; a file, directory or glob pattern, or "-" for the standard input
+	TrainListFile string  // -train-list: file listing training files, directories or glob patterns
+	OutputFile    string  // -output: file receiving the vectors / classes
+	SaveVocabFile string  // -save-vocab: file receiving the vocabulary
+	ReadVocabFile string  // -read-vocab: vocabulary file used instead of the training data
+	Size          int     // -size: size of character vectors
+	Window        int     // -window: max skip length between characters
+	Sample        float64 // -sample: threshold for down-sampling frequent characters
+	HS            int     // -hs: use Hierarchical Softmax
+	Negative      int     // -negative: number of negative examples
+	Threads       int     // -threads: number of training goroutines
+	Iter          int     // -iter: number of training iterations
+	MinCount      int64   // -min-count: discard characters appearing less than this
+	Alpha         float64 // -alpha: starting learning rate
+	Classes       int     // -classes: number of K-means classes (0 = write vectors)
+	Debug         int     // -debug: debug mode
+	Binary        int     // -binary: save the vectors in binary mode
+	CBOW          int     // -cbow: use the continuous bag of characters model
+	SentenceBreak string  // -sentence-break: characters that end a sentence
+	Normalize     string  // -normalize: Unicode normalization of the text (NormalizeNFC etc.)
+	Unit          string  // -unit: unit of training, UnitChar ("" too) or UnitGrapheme
+	NGram         []int   // -ngram: lengths of the n-grams of units added to the vocabulary
+	NGramMinCount int64   // -ngram-min-count: discard n-grams appearing less than this
+	NGramMode     string  // -ngram-mode: tokenization into n-grams, NGramGreedy ("" too) or NGramOverlap
+
+	StreamVocabSize int64 // -stream-vocab-size: bytes of the standard input read to learn the vocabulary
+	StreamChars     int64 // -stream-chars: characters expected on the standard input, over which alpha decreases
+
+	MaxVocab int    // -max-vocab: keep only this many of the most frequent characters (0 = no limit)
+	KeepFile string // -keep: characters kept in the vocabulary whatever their count
+
+	ComponentsFile string // -components: table of the components of characters
+	Decompose      int    // -decompose: use the canonical decomposition as components
+
+	OutputComponentsFile string // -output-components: file receiving the vectors of the components
+
+	CheckpointFile  string        // -checkpoint: file receiving the training state
+	CheckpointEvery time.Duration // -checkpoint-every: interval between checkpoints
+	CheckpointChars int64         // -checkpoint-every: characters between checkpoints
+	ResumeFile      string        // -resume: checkpoint to continue training from
+
+	OutputContextFile  string // -output-context: file receiving the syn1neg vectors, or syn1 without negative sampling
+	OutputCombinedFile string // -output-combined: file receiving (syn0+syn1neg)/2
+
+	InitModelFile   string // -init-model: model or checkpoint to seed the weights from
+	InitContextFile string // -init-context: context vectors seeding syn1neg with a vectors InitModelFile
+	Freeze          int    // -freeze: do not update the characters of InitModelFile
+
+	Seed          int64 // -seed: seed of the random numbers; 0 uses those of word2vec
+	Deterministic int   // -deterministic: train the sentences of the goroutines in turn, reproducibly
+
+	// Log receives progress and debug messages; nil means os.Stderr.
+	Log io.Writer
+}
+
+// DefaultConfig returns the default parameters of the char2vec command.
+func DefaultConfig() Config {
+	return Config{
+		Size:     100,
+		Window:   5,
+		Sample:   1e-3,
+		HS:       0,
+		Negative: 5,
+		Threads:  12,
+		Iter:     5,
+		MinCount: 5,
+		Alpha:    0.05,
+		Debug:    2,
+		Binary:   0,
+		CBOW:     1,
+
+		SentenceBreak: "\n",
+		NGramMinCount: 5,
+
+		StreamVocabSize: 256 << 20,
+
+		CheckpointEvery: 30 * time.Minute,
+	}
+}
+
+func (c *Config) log() io.Writer {
+	if c.Log == nil {
+		return os.Stderr
+	}
+	return c.Log
+}
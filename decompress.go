@@ -0,0 +1,77 @@
+package char2vec
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// A Decompressor reads a compressed format of training and vectors files.
+type Decompressor struct {
+	Name      string
+	Extension string // file name extension, e.g. ".gz"
+	Magic     []byte // first bytes of a compressed stream
+	// NewReader returns a reader of the decompressed stream. When the
+	// reader is an io.Closer, it is closed at the end of the stream.
+	NewReader func(r io.Reader) (io.Reader, error)
+}
+
+var decompressors = []*Decompressor{
+	{Name: "bzip2", Extension: ".bz2", Magic: []byte("BZh"), NewReader: func(r io.Reader) (io.Reader, error) {
+		return bzip2.NewReader(r), nil
+	}},
+	{Name: "gzip", Extension: ".gz", Magic: []byte{0x1f, 0x8b}, NewReader: func(r io.Reader) (io.Reader, error) {
+		return gzip.NewReader(r)
+	}},
+	{Name: "xz", Extension: ".xz", Magic: []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}, NewReader: func(r io.Reader) (io.Reader, error) {
+		return xz.NewReader(r)
+	}},
+	{Name: "zstd", Extension: ".zst", Magic: []byte{0x28, 0xb5, 0x2f, 0xfd}, NewReader: func(r io.Reader) (io.Reader, error) {
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	}},
+}
+
+// RegisterDecompressor adds a compressed format, which takes precedence
+// over those of the same extension or magic bytes. It must be called
+// before training or loading vectors.
+func RegisterDecompressor(d *Decompressor) {
+	decompressors = append([]*Decompressor{d}, decompressors...)
+}
+
+// decompressorOf returns the decompressor of a file, by the extension of
+// its name or, when the extension is unknown, by the magic bytes at the
+// start of br; nil when the file is not compressed.
+func decompressorOf(name string, br *bufio.Reader) *Decompressor {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, d := range decompressors {
+		if ext != "" && ext == d.Extension {
+			return d
+		}
+	}
+	for _, d := range decompressors {
+		if magic, _ := br.Peek(len(d.Magic)); len(d.Magic) > 0 && bytes.Equal(magic, d.Magic) {
+			return d
+		}
+	}
+	return nil
+}
+
+// decompress wraps br with the decompressor of the file name, or of the
+// magic bytes at the start of the stream.
+func decompress(name string, br *bufio.Reader) (io.Reader, error) {
+	if d := decompressorOf(name, br); d != nil {
+		return d.NewReader(br)
+	}
+	return br, nil
+}
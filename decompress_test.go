@@ -0,0 +1,66 @@
+package char2vec
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDecompressorOf(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		data string
+		want string
+	}{
+		{"corpus.txt.gz", "", "gzip"},
+		{"corpus.txt.BZ2", "", "bzip2"},
+		{"corpus.txt.xz", "", "xz"},
+		{"corpus.txt.zst", "", "zstd"},
+		{"corpus", "\x1f\x8b\x08", "gzip"},
+		{"corpus", "BZh91AY", "bzip2"},
+		{"corpus", "\xfd7zXZ\x00\x00", "xz"},
+		{"-", "\x28\xb5\x2f\xfd\x00", "zstd"},
+		{"corpus.txt", "あいう\n", ""},
+		{"corpus", "", ""},
+	} {
+		d := decompressorOf(test.name, bufio.NewReader(strings.NewReader(test.data)))
+		got := ""
+		if d != nil {
+			got = d.Name
+		}
+		if got != test.want {
+			t.Errorf("decompressorOf(%q, %q) = %q, want %q", test.name, test.data, got, test.want)
+		}
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	const text = "あいうえお\nかきくけこ\n"
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(text))
+	zw.Close()
+	for _, name := range []string{"corpus.gz", "-"} {
+		r, err := decompress(name, bufio.NewReader(bytes.NewReader(buf.Bytes())))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != text {
+			t.Errorf("decompress(%q) = %q, want %q", name, got, text)
+		}
+	}
+	r, err := decompress("corpus.txt", bufio.NewReader(strings.NewReader(text)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := io.ReadAll(r); string(got) != text {
+		t.Errorf("decompress of plain text = %q, want %q", got, text)
+	}
+}
@@ -0,0 +1,51 @@
+package char2vec
+
+import "context"
+
+// seedRandom returns the initial state of the random number generator
+// stream of a computation: stream itself without a seed, as in word2vec,
+// or the stream mixed with the seed (SplitMix64).
+func seedRandom(seed int64, stream uint64) uint64 {
+	if seed == 0 {
+		return stream
+	}
+	z := uint64(seed) + (stream+1)*0x9E3779B97F4A7C15
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+	return z ^ (z >> 31)
+}
+
+// waitTurn lets the training goroutines train their sentences in turn
+// when Deterministic is set, so that the updates of the shared weights
+// and alpha happen in the same order in every run. A goroutine calls it
+// between two sentences; when held, it passes the turn to the next
+// goroutine first. Goroutines waiting for their turn count as paused
+// for saveCheckpoint. When training is cancelled, the turn stays with the
+// goroutine that would train the next sentence, for readCheckpoint.
+func (t *Trainer) waitTurn(ctx context.Context, id int, held bool) error {
+	t.pause_mu.Lock()
+	defer t.pause_mu.Unlock()
+	if held {
+		t.nextTurn()
+	}
+	t.paused++
+	t.pause_cond.Broadcast()
+	for t.turn != id && ctx.Err() == nil {
+		t.pause_cond.Wait()
+	}
+	t.paused--
+	return ctx.Err()
+}
+
+// nextTurn passes the turn to the next goroutine that has not finished.
+// pause_mu must be held.
+func (t *Trainer) nextTurn() {
+	threads := len(t.finished)
+	for a := 1; a <= threads; a++ {
+		if next := (t.turn + a) % threads; !t.finished[next] {
+			t.turn = next
+			break
+		}
+	}
+	t.pause_cond.Broadcast()
+}
@@ -0,0 +1,20 @@
+package char2vec
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDeterministic(t *testing.T) {
+	cfg := testConfig(writeTestCorpus(t, 300))
+	var models []*Model
+	for a := 0; a < 2; a++ {
+		m, err := newTestTrainer(cfg).Train(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		models = append(models, m)
+	}
+	sameVectors(t, "vectors", models[1].Vectors, models[0].Vectors)
+	sameVectors(t, "context", models[1].Context, models[0].Context)
+}
@@ -0,0 +1,100 @@
+package char2vec
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// escapeChar returns the token written for a character in the word2vec
+// compatible format and in vocabulary files. Readers split tokens at
+// whitespace, so the </s> slot is written as "</s>", and whitespace and
+// control characters as "U+XXXX".
+func escapeChar(char rune) string {
+	if char == 0 {
+		return "</s>"
+	}
+	if unicode.IsSpace(char) || unicode.IsControl(char) || char == utf8.RuneError {
+		return fmt.Sprintf("U+%04X", char)
+	}
+	return string(char)
+}
+
+// escapeToken returns the token written for a vocabulary entry. An entry
+// of several characters containing a character that escapeChar escapes is
+// written as the "U+XXXX" tokens of all its characters, e.g.
+// "U+000DU+000A" for a CR LF grapheme cluster, as are the n-grams that
+// would read as escapes, such as "U+2" or "</s>".
+func escapeToken(token string) string {
+	if char, l := utf8.DecodeRuneInString(token); l == len(token) {
+		return escapeChar(char)
+	}
+	escaped := isEscape(token)
+	for _, char := range token {
+		if char == 0 || escapeChar(char) != string(char) {
+			escaped = true
+		}
+	}
+	if !escaped {
+		return token
+	}
+	var b strings.Builder
+	for _, char := range token {
+		fmt.Fprintf(&b, "U+%04X", char)
+	}
+	return b.String()
+}
+
+// textToken returns the token written for a vocabulary entry in the
+// formats that write characters as they are: only the entries that would
+// read as escapes are escaped.
+func textToken(token string) string {
+	if utf8.RuneCountInString(token) > 1 && isEscape(token) {
+		return escapeToken(token)
+	}
+	return token
+}
+
+// isEscape reports whether a token reads as an escape.
+func isEscape(token string) bool {
+	return strings.HasPrefix(token, "U+") || token == "</s>"
+}
+
+// unescapeToken returns the vocabulary entry of a token written by
+// escapeToken.
+func unescapeToken(token string) (string, bool) {
+	if char, ok := unescapeChar(token); ok {
+		return string(char), true
+	}
+	if !strings.HasPrefix(token, "U+") {
+		return token, token != ""
+	}
+	var b strings.Builder
+	for _, field := range strings.Split(token, "U+")[1:] {
+		v, err := strconv.ParseUint(field, 16, 32)
+		if err != nil || v > unicode.MaxRune {
+			return token, true
+		}
+		b.WriteRune(rune(v))
+	}
+	return b.String(), true
+}
+
+// unescapeChar returns the character of a token written by escapeChar.
+func unescapeChar(token string) (rune, bool) {
+	if char, l := utf8.DecodeRuneInString(token); l == len(token) && l > 0 {
+		return char, true
+	}
+	if token == "</s>" {
+		return 0, true
+	}
+	if strings.HasPrefix(token, "U+") {
+		v, err := strconv.ParseUint(token[2:], 16, 32)
+		if err == nil && v <= unicode.MaxRune {
+			return rune(v), true
+		}
+	}
+	return 0, false
+}
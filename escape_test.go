@@ -0,0 +1,72 @@
+package char2vec
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEscapeChar(t *testing.T) {
+	tests := []struct {
+		char rune
+		want string
+	}{
+		{0, "</s>"},
+		{' ', "U+0020"},
+		{'\n', "U+000A"},
+		{'\t', "U+0009"},
+		{'　', "U+3000"},
+		{'\u0085', "U+0085"},
+		{'�', "U+FFFD"},
+		{'a', "a"},
+		{'あ', "あ"},
+		{'U', "U"},
+	}
+	for _, test := range tests {
+		got := escapeChar(test.char)
+		if got != test.want {
+			t.Errorf("escapeChar(%q) = %q, want %q", test.char, got, test.want)
+		}
+		if char, ok := unescapeChar(got); !ok || char != test.char {
+			t.Errorf("unescapeChar(%q) = %q, %v, want %q", got, char, ok, test.char)
+		}
+	}
+}
+
+func TestEscapeNGrams(t *testing.T) {
+	for _, token := range []string{"U+2", "U+", "</s>", "U+0041", "a</s>", "U+\n"} {
+		escaped := escapeToken(token)
+		if got, ok := unescapeToken(escaped); !ok || got != token {
+			t.Errorf("escapeToken(%q) = %q, read back as %q", token, escaped, got)
+		}
+		written := textToken(token)
+		if got, ok := unescapeToken(written); !ok || got != token {
+			t.Errorf("textToken(%q) = %q, read back as %q", token, written, got)
+		}
+	}
+}
+
+func TestWriteVectorsNGrams(t *testing.T) {
+	vocab := []string{sentence_end, "U", "U+2", "</s>", " "}
+	m := newModel(vocab, 2, make([]float64, len(vocab)*2))
+	for _, format := range []Format{FormatText, FormatBinary, FormatWord2Vec} {
+		var buf bytes.Buffer
+		if err := m.WriteVectors(&buf, format); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(t.TempDir(), "vectors")
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := LoadFormat(path, format)
+		if err != nil {
+			t.Fatalf("format %d: %v", format, err)
+		}
+		for a, char := range vocab {
+			if got.Vocab[a] != char {
+				t.Errorf("format %d: character %d read as %q, want %q", format, a, got.Vocab[a], char)
+			}
+		}
+	}
+}
@@ -0,0 +1,394 @@
+package char2vec
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// expandTrainFile returns the files of a -train argument: the regular
+// files under a directory, the files matching a glob pattern, or the
+// file itself, which may be "-" for the standard input.
+func expandTrainFile(name string) ([]string, error) {
+	if name == stdin_file {
+		return []string{name}, nil
+	}
+	fi, err := os.Stat(name)
+	if err == nil && fi.IsDir() {
+		var files []string
+		err = filepath.WalkDir(name, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.Type().IsRegular() {
+				files = append(files, path)
+			}
+			return nil
+		})
+		return files, err
+	}
+	if err == nil {
+		return []string{name}, nil
+	}
+	files, err := filepath.Glob(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, errors.New("ERROR: training data file not found!")
+	}
+	return files, nil
+}
+
+// initTrainFiles expands TrainFile and the entries of TrainListFile, one
+// file, directory or glob pattern per line, into the list of training
+// files. BuildVocab, Plan and Train call it.
+func (t *Trainer) initTrainFiles() error {
+	var names []string
+	if t.cfg.TrainFile != "" {
+		names = append(names, t.cfg.TrainFile)
+	}
+	if t.cfg.TrainListFile != "" {
+		data, err := os.ReadFile(t.cfg.TrainListFile)
+		if err != nil {
+			return err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				names = append(names, line)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return errors.New("ERROR: training data file not found!")
+	}
+	t.files = nil
+	for _, name := range names {
+		files, err := expandTrainFile(name)
+		if err != nil {
+			return err
+		}
+		t.files = append(t.files, files...)
+	}
+	if len(t.files) == 0 {
+		return errors.New("ERROR: training data file not found!")
+	}
+	t.file_sizes = make([]int64, len(t.files))
+	for a, file := range t.files {
+		if file == stdin_file {
+			if len(t.files) > 1 {
+				return errors.New("the standard input cannot be read with other training files")
+			}
+			return t.initStream()
+		}
+		fi, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		t.file_sizes[a] = fi.Size()
+	}
+	return nil
+}
+
+// decompressTrainFiles decompresses the compressed training files once,
+// into temporary files that the vocabulary pass and the goroutines then
+// read, the latter from their own offsets; a compressed stream can only
+// be read from its start. Several files are decompressed in parallel.
+// BuildVocab and Train call it, and remove the temporary files when they
+// end.
+func (t *Trainer) decompressTrainFiles(ctx context.Context) error {
+	if t.streaming() {
+		t.data_files = t.files
+		return nil
+	}
+	t.data_files = make([]string, len(t.files))
+	errs := make([]error, len(t.files))
+	var wg sync.WaitGroup
+	var next int64 = -1
+	for a := 0; a < t.cfg.Threads && a < len(t.files); a++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= len(t.files) {
+					return
+				}
+				t.data_files[i], errs[i] = decompressFile(ctx, t.files[i])
+				if errs[i] == nil && t.data_files[i] != t.files[i] && t.cfg.Debug > 0 {
+					fmt.Fprintf(t.log, "Decompressed %s\n", t.files[i])
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.removeDataFiles()
+			return err
+		}
+	}
+	t.data_sizes = make([]int64, len(t.data_files))
+	for a, file := range t.data_files {
+		fi, err := os.Stat(file)
+		if err != nil {
+			t.removeDataFiles()
+			return err
+		}
+		t.data_sizes[a] = fi.Size()
+	}
+	return nil
+}
+
+// decompressFile decompresses a compressed file into a temporary file,
+// and returns the name of the latter, or that of the file itself when it
+// is not compressed.
+func decompressFile(ctx context.Context, file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	br := bufio.NewReader(f)
+	d := decompressorOf(file, br)
+	if d == nil {
+		return file, nil
+	}
+	dr, err := d.NewReader(br)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", file, err)
+	}
+	if c, ok := dr.(io.Closer); ok {
+		defer c.Close()
+	}
+	tmp, err := os.CreateTemp("", "char2vec-*-"+strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)))
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(tmp, contextReader{ctx, dr})
+	if e := tmp.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("%s: %v", file, err)
+	}
+	return tmp.Name(), nil
+}
+
+// contextReader reads from r until ctx is cancelled.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// removeDataFiles removes the temporary files of decompressTrainFiles.
+func (t *Trainer) removeDataFiles() {
+	for a, file := range t.data_files {
+		if file != "" && file != t.files[a] {
+			os.Remove(file)
+		}
+	}
+	t.data_files = nil
+}
+
+// filePart is a byte range of a training file read by a goroutine.
+type filePart struct {
+	path  string
+	start int64
+	end   int64 // -1 for the end of the file
+}
+
+// wholeFiles returns the parts reading the given files whole.
+func wholeFiles(paths []string) []filePart {
+	parts := make([]filePart, len(paths))
+	for a, path := range paths {
+		parts[a] = filePart{path: path, end: -1}
+	}
+	return parts
+}
+
+// splitTrainFiles splits the training files, taken one after the other,
+// into as many byte ranges of about the same size as goroutines. The
+// ranges end at line ends, or at character boundaries when a line is
+// longer than max_line_scan, so that every character is read by one
+// goroutine once; a goroutine reads the parts of the files in its range
+// in turn. Train calls it after decompressTrainFiles.
+func (t *Trainer) splitTrainFiles() error {
+	threads := t.cfg.Threads
+	t.thread_parts = make([][]filePart, threads)
+	if t.streaming() {
+		return nil
+	}
+	var total int64
+	for _, size := range t.data_sizes {
+		total += size
+	}
+	// The goroutines start at the cuts: file and offset in the file
+	cut_files := make([]int, threads+1)
+	cut_offsets := make([]int64, threads+1)
+	cut_files[threads] = len(t.data_files) - 1
+	cut_offsets[threads] = t.data_sizes[len(t.data_sizes)-1]
+	var file_start int64 // offset of file i in the concatenation
+	i := 0
+	for a := 1; a < threads; a++ {
+		pos := total / int64(threads) * int64(a)
+		for i+1 < len(t.data_files) && pos >= file_start+t.data_sizes[i] {
+			file_start += t.data_sizes[i]
+			i++
+		}
+		offset, err := alignFileOffset(t.data_files[i], pos-file_start)
+		if err != nil {
+			return err
+		}
+		cut_files[a], cut_offsets[a] = i, offset
+		if i == cut_files[a-1] && offset < cut_offsets[a-1] {
+			cut_offsets[a] = cut_offsets[a-1]
+		}
+	}
+	for a := 0; a < threads; a++ {
+		for i := cut_files[a]; i <= cut_files[a+1]; i++ {
+			part := filePart{path: t.data_files[i], end: t.data_sizes[i]}
+			if i == cut_files[a] {
+				part.start = cut_offsets[a]
+			}
+			if i == cut_files[a+1] {
+				part.end = cut_offsets[a+1]
+			}
+			if part.start < part.end || (i == cut_files[a+1] && len(t.thread_parts[a]) == 0) {
+				t.thread_parts[a] = append(t.thread_parts[a], part)
+			}
+		}
+	}
+	return nil
+}
+
+// alignFileOffset is alignOffset on the named file.
+func alignFileOffset(path string, pos int64) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return alignOffset(f, pos)
+}
+
+const max_line_scan int = 1 << 20 // bytes searched for a line end by alignOffset
+
+// alignOffset returns the start of the line following offset pos, or the
+// start of the character at pos when there is no line end close by.
+func alignOffset(f *os.File, pos int64) (int64, error) {
+	if pos == 0 {
+		return 0, nil
+	}
+	if _, err := f.Seek(pos-1, SEEK_SET); err != nil {
+		return 0, err
+	}
+	br := bufio.NewReader(io.LimitReader(f, int64(max_line_scan)+1))
+	prev, err := br.ReadByte()
+	if err != nil {
+		return pos, nil
+	}
+	var skipped int64
+	for a := 0; a < max_line_scan; a++ {
+		if prev == '\n' {
+			return pos + int64(a), nil
+		}
+		if prev, err = br.ReadByte(); err != nil {
+			break
+		}
+		if skipped == int64(a) && prev&0xC0 == 0x80 {
+			// A continuation byte of the character at pos
+			skipped++
+		}
+	}
+	return pos + skipped, nil
+}
+
+// threadStart returns the offset at which a training goroutine starts
+// reading its first part in every iteration.
+func (t *Trainer) threadStart(id int) int64 {
+	if len(t.thread_parts[id]) == 0 {
+		return 0
+	}
+	return t.thread_parts[id][0].start
+}
+
+// prepareTrainFileReader returns a reader of the training files of a
+// goroutine, starting at offset pos of the file of its file-th part, or
+// of its share of the standard input.
+func (t *Trainer) prepareTrainFileReader(id int, file int, pos int64) (*trainReader, error) {
+	r, err := t.newTrainReader(t.thread_parts[id])
+	if err != nil {
+		return nil, err
+	}
+	if t.streaming() {
+		r.Reader = bufio.NewReader(&chunkReader{t: t})
+		return r, nil
+	}
+	if err := r.open(file, pos); err != nil {
+		r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// open starts reading the file-th part at offset pos of its file.
+func (r *trainReader) open(file int, pos int64) error {
+	r.Close()
+	path, end := r.parts[file].path, r.parts[file].end
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	r.f, r.file, r.pos = f, file, pos
+	if end >= 0 {
+		// The goroutine's share of the file
+		r.Reader = bufio.NewReader(io.NewSectionReader(f, pos, end-pos))
+		return nil
+	}
+	if _, err := f.Seek(pos, SEEK_SET); err != nil {
+		return err
+	}
+	r.Reader = bufio.NewReader(f)
+	return nil
+}
+
+// readRaw reads a character of the training files. The end of a part
+// that is followed by another one is read as the </s> slot, so that
+// sentences do not run across files.
+func (r *trainReader) readRaw() (rune, int, error) {
+	char, size, err := r.Reader.ReadRune()
+	if err == io.EOF && r.file+1 < len(r.parts) {
+		if err := r.open(r.file+1, r.parts[r.file+1].start); err != nil {
+			return 0, 0, err
+		}
+		return 0, 0, nil
+	}
+	return char, size, err
+}
+
+// Close closes the file being read.
+func (r *trainReader) Close() error {
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
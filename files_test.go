@@ -0,0 +1,105 @@
+package char2vec
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInitTrainFilesEmpty(t *testing.T) {
+	tr := NewTrainer(Config{TrainFile: t.TempDir(), Log: io.Discard})
+	if err := tr.initTrainFiles(); err == nil {
+		t.Error("no error for a directory without files")
+	}
+}
+
+func TestSplitTrainFiles(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	var sizes []int64
+	for a, lines := range []int{300, 5, 0, 120} {
+		path := filepath.Join(dir, fmt.Sprintf("part%d", a))
+		text := strings.Repeat("あいうえお abc\n", lines)
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+		sizes = append(sizes, int64(len(text)))
+	}
+	for _, threads := range []int{1, 2, 3, 12, 100} {
+		tr := NewTrainer(Config{Threads: threads})
+		tr.files, tr.data_files, tr.data_sizes = paths, paths, sizes
+		if err := tr.splitTrainFiles(); err != nil {
+			t.Fatal(err)
+		}
+		if len(tr.thread_parts) != threads {
+			t.Fatalf("%d threads: %d goroutines", threads, len(tr.thread_parts))
+		}
+		// The parts cover the files in order, once, from line starts
+		file, pos := 0, int64(0)
+		for a, parts := range tr.thread_parts {
+			if len(parts) == 0 {
+				t.Fatalf("%d threads: goroutine %d has no part", threads, a)
+			}
+			for _, part := range parts {
+				if part.start == part.end {
+					continue
+				}
+				for file < len(paths) && (paths[file] != part.path || pos == sizes[file]) {
+					if pos != sizes[file] {
+						t.Fatalf("%d threads: %s read up to %d of %d", threads, paths[file], pos, sizes[file])
+					}
+					file, pos = file+1, 0
+				}
+				if file == len(paths) || part.start != pos {
+					t.Fatalf("%d threads: goroutine %d reads %s from %d, want %d", threads, a, part.path, part.start, pos)
+				}
+				if pos%int64(len("あいうえお abc\n")) != 0 {
+					t.Errorf("%d threads: goroutine %d starts in a line", threads, a)
+				}
+				pos = part.end
+			}
+		}
+		if file != len(paths)-1 || pos != sizes[file] {
+			t.Errorf("%d threads: read up to %d of file %d", threads, pos, file)
+		}
+	}
+}
+
+func TestAlignOffset(t *testing.T) {
+	dir := t.TempDir()
+	lines := filepath.Join(dir, "lines")
+	if err := os.WriteFile(lines, []byte("ab\nあいう\nc"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	chars := filepath.Join(dir, "chars")
+	if err := os.WriteFile(chars, []byte("あいう"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		path      string
+		pos, want int64
+	}{
+		{lines, 0, 0},
+		{lines, 1, 3},  // the start of the next line
+		{lines, 3, 3},  // already a line start
+		{lines, 4, 13}, // within "あいう"
+		{lines, 13, 13},
+		{lines, 14, 14}, // the end of the file
+		{chars, 1, 3},   // no line end: the next character
+		{chars, 3, 3},
+		{chars, 8, 9},
+		{chars, 9, 9},
+	} {
+		got, err := alignFileOffset(test.path, test.pos)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("alignFileOffset(%s, %d) = %d, want %d", filepath.Base(test.path), test.pos, got, test.want)
+		}
+	}
+}
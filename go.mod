@@ -0,0 +1,10 @@
+module github.com/koji-ohki-1974/char2vec
+
+go 1.25.0
+
+require (
+	github.com/klauspost/compress v1.18.0
+	github.com/rivo/uniseg v0.4.7
+	github.com/ulikunitz/xz v0.5.15
+	golang.org/x/text v0.40.0
+)
@@ -0,0 +1,138 @@
+package char2vec
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// initModel is the model read from InitModelFile.
+type initModel struct {
+	chars     []string
+	size      int
+	syn0      []float32
+	syn1neg   []float32 // nil for a vectors file without context vectors
+	normalize string
+	unit      string
+	exact     bool // normalize and unit are recorded, as in a checkpoint
+}
+
+// readInitModel reads the characters and the weights of a model to
+// continue training from. A checkpoint provides both syn0 and syn1neg;
+// a vectors file provides syn0, and syn1neg comes from the context
+// vectors of context_file when that is set.
+func readInitModel(file, context_file string) (*initModel, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if cp, err := decodeCheckpoint(bufio.NewReader(f)); err == nil {
+		if context_file != "" {
+			return nil, fmt.Errorf("%s: the checkpoint has its own context vectors", context_file)
+		}
+		return &initModel{cp.Tokens, cp.Size, cp.Syn0, cp.Syn1neg, cp.Normalize, cp.Unit, true}, nil
+	}
+	m, err := Load(file)
+	if err != nil {
+		return nil, err
+	}
+	if context_file != "" {
+		if err := m.LoadContext(context_file); err != nil {
+			return nil, err
+		}
+	}
+	return &initModel{m.Vocab, m.Size, float32s(m.Vectors), float32s(m.Context), m.Normalization, m.Unit, false}, nil
+}
+
+// checkInitModel checks that the model was trained on text normalized and
+// split into units as the training file. Vectors written by other tools
+// do not record them, and are not checked then.
+func (t *Trainer) checkInitModel(m *initModel) error {
+	unit, model_unit := t.cfg.Unit, m.unit
+	if unit == "" {
+		unit = UnitChar
+	}
+	if model_unit == "" {
+		model_unit = UnitChar
+	}
+	if (m.exact || m.normalize != "") && m.normalize != t.cfg.Normalize {
+		return fmt.Errorf("%s: the model is normalized with %q, not %q", t.cfg.InitModelFile, m.normalize, t.cfg.Normalize)
+	}
+	if (m.exact || model_unit != UnitChar) && model_unit != unit {
+		return fmt.Errorf("%s: the model has the unit %s, not %s", t.cfg.InitModelFile, model_unit, unit)
+	}
+	return nil
+}
+
+// initFromModel merges the characters of InitModelFile into the
+// vocabulary and seeds their rows of syn0 and syn1neg from it, and from
+// InitContextFile for the syn1neg of a vectors file. Characters
+// that only occur in the training file keep their random initial vectors.
+// Characters that only occur in the model are kept with a count of
+// MinCount, so that the Huffman tree stays balanced and negative sampling
+// still draws them; they do not count in train_chars. syn1 is not seeded,
+// as the Huffman tree depends on the new counts. Train calls it instead
+// of initNet when InitModelFile is set.
+func (t *Trainer) initFromModel() error {
+	fmt.Fprintln(t.log, "InitFromModel")
+	m, err := readInitModel(t.cfg.InitModelFile, t.cfg.InitContextFile)
+	if err != nil {
+		return fmt.Errorf("%s: %v", t.cfg.InitModelFile, err)
+	}
+	if err := t.checkInitModel(m); err != nil {
+		return err
+	}
+	chars, size, syn0, syn1neg := m.chars, m.size, m.syn0, m.syn1neg
+	if size != t.cfg.Size {
+		fmt.Fprintf(t.log, "Using size %d of %s\n", size, t.cfg.InitModelFile)
+		t.cfg.Size = size
+	}
+	layer1_size := size
+	min_count := t.cfg.MinCount
+	if min_count < 1 {
+		min_count = 1
+	}
+	added := 0
+	t.vocab = t.vocab[:t.vocab_size]
+	for _, char := range chars {
+		if t.searchVocab(char) != -1 {
+			continue
+		}
+		t.vocab = append(t.vocab, vocab_char{
+			cn:    min_count,
+			char:  char,
+			code:  make([]byte, MAX_CODE_LENGTH),
+			point: make([]int, MAX_CODE_LENGTH),
+		})
+		t.vocab_size++
+		added++
+	}
+	// createBinaryTree needs the counts in decreasing order; </s> stays first
+	sort.Stable(t.vocab[1:])
+	t.vocab = append(t.vocab, vocab_char{})
+	t.vocab_hash = map[string]int{}
+	for a := 0; a < t.vocab_size; a++ {
+		t.vocab_hash[t.vocab[a].char] = a
+	}
+	t.initNet()
+	if t.cfg.Freeze != 0 {
+		t.frozen = make([]bool, t.vocab_size)
+	}
+	for b, char := range chars {
+		a := t.searchVocab(char)
+		copy(t.syn0[a*layer1_size:(a+1)*layer1_size], syn0[b*layer1_size:(b+1)*layer1_size])
+		if t.syn1neg != nil && syn1neg != nil {
+			copy(t.syn1neg[a*layer1_size:(a+1)*layer1_size], syn1neg[b*layer1_size:(b+1)*layer1_size])
+		}
+		if t.frozen != nil {
+			t.frozen[a] = true
+		}
+	}
+	if t.cfg.Debug > 0 {
+		fmt.Fprintf(t.log, "Characters from %s: %d (%d not in the training file)\n", t.cfg.InitModelFile, len(chars), added)
+		fmt.Fprintf(t.log, "Vocab size: %d\n", t.vocab_size)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package char2vec
+
+import (
+	"testing"
+)
+
+func TestReadInitModelContext(t *testing.T) {
+	m := testModel()
+	c := testModel()
+	// The context vectors are in another order, and miss a character
+	c.Vocab = []string{"a", sentence_end, " ", "\n", "あ"}
+	c.Vectors = c.Vectors[:len(c.Vocab)*c.Size]
+	c = newModel(c.Vocab, c.Size, c.Vectors)
+	im, err := readInitModel(writeTestVectors(t, m, FormatBinary), writeTestVectors(t, c, FormatBinary))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(im.syn1neg) != len(m.Vectors) {
+		t.Fatalf("syn1neg of %d values, want %d", len(im.syn1neg), len(m.Vectors))
+	}
+	for a, char := range m.Vocab {
+		for b := 0; b < m.Size; b++ {
+			want := float32(0)
+			if i := c.Index(char); i != -1 {
+				want = float32(c.Vectors[i*c.Size+b])
+			}
+			if got := im.syn1neg[a*m.Size+b]; got != want {
+				t.Errorf("syn1neg of %q: value %d is %g, want %g", char, b, got, want)
+			}
+		}
+	}
+	im, err = readInitModel(writeTestVectors(t, m, FormatText), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if im.syn1neg != nil {
+		t.Error("syn1neg read without context vectors")
+	}
+}
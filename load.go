@@ -0,0 +1,232 @@
+package char2vec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Format is the layout of a vectors file. The values match the -binary
+// option of the char2vec command.
+type Format int
+
+const (
+	FormatAuto     Format = -1 // detect the format from the file contents
+	FormatText     Format = 0  // a character and its values as text per row
+	FormatBinary   Format = 1  // a character and its float64 values per row
+	FormatWord2Vec Format = 2  // word2vec binary format with float32 values
+)
+
+const vectors_trailer string = "#char2vec" // Start of the line of metadata following the vectors
+
+// Load reads character vectors written by the char2vec command. The text
+// and binary formats, float32 (word2vec) and float64 binary values, and
+// bzip2, gzip, xz or zstd compression are detected from the file contents
+// or name, as are the formats of RegisterDecompressor.
+func Load(path string) (*Model, error) {
+	return LoadFormat(path, FormatAuto)
+}
+
+// LoadFormat is like Load, but reads the vectors in the given format
+// unless format is FormatAuto.
+func LoadFormat(path string, format Format) (*Model, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := decompress(path, bufio.NewReader(f))
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := r.(io.Closer); ok {
+		defer c.Close()
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var chars, size int
+	n, err := fmt.Sscanf(string(firstLine(data)), "%d %d", &chars, &size)
+	if n != 2 {
+		return nil, fmt.Errorf("%s: invalid header: %v", path, err)
+	}
+	data = data[len(firstLine(data)):]
+	if len(data) > 0 {
+		data = data[1:]
+	}
+	if format == FormatAuto {
+		switch {
+		case isBinary(data, chars, size, 8):
+			format = FormatBinary
+		case isBinary(data, chars, size, 4):
+			format = FormatWord2Vec
+		default:
+			format = FormatText
+		}
+	}
+	var vocab []string
+	var vectors []float64
+	var end int // end of the vectors
+	switch format {
+	case FormatBinary:
+		if !isBinary(data, chars, size, 8) {
+			return nil, fmt.Errorf("%s: not in the binary format", path)
+		}
+		vocab, vectors, end = parseBinary(data, chars, size, 8)
+	case FormatWord2Vec:
+		if !isBinary(data, chars, size, 4) {
+			return nil, fmt.Errorf("%s: not in the word2vec binary format", path)
+		}
+		vocab, vectors, end = parseBinary(data, chars, size, 4)
+	default:
+		vocab, vectors, end, err = parseText(data, chars, size)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	m := newModel(vocab, size, vectors)
+	if end < len(data) && isTrailer(data[end:]) {
+		for _, field := range strings.Fields(string(firstLine(data[end:])))[1:] {
+			if v, ok := strings.CutPrefix(field, "normalize="); ok {
+				m.Normalization = v
+			}
+			if v, ok := strings.CutPrefix(field, "unit="); ok {
+				m.Unit = v
+			}
+			if v, ok := strings.CutPrefix(field, "seed="); ok {
+				m.Seed, _ = strconv.ParseInt(v, 10, 64)
+			}
+			if v, ok := strings.CutPrefix(field, "deterministic="); ok {
+				m.Deterministic = v == "1"
+			}
+			if v, ok := strings.CutPrefix(field, "partial="); ok {
+				m.Partial = v == "1"
+			}
+		}
+	}
+	if m.Unit == "" {
+		// Vectors of other tools do not record the unit
+		for _, char := range vocab {
+			if utf8.RuneCountInString(char) > 1 {
+				m.Unit = UnitGrapheme
+				break
+			}
+		}
+	}
+	return m, nil
+}
+
+func firstLine(data []byte) []byte {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return data[:i]
+	}
+	return data
+}
+
+// readChar reads the character token starting at data[p] and the space
+// following it, and returns the vocabulary entry and the position after
+// the space. The first character always belongs to the token, so that a
+// space character written as is can be read back.
+func readChar(data []byte, p int) (string, int, bool) {
+	_, l := utf8.DecodeRune(data[p:])
+	q := bytes.IndexByte(data[p+l:], ' ')
+	if l == 0 || q < 0 {
+		return "", 0, false
+	}
+	char, ok := unescapeToken(string(data[p : p+l+q]))
+	return char, p + l + q + 1, ok
+}
+
+// isTrailer reports whether data starts with the line of metadata that
+// WriteVectors writes after the vectors.
+func isTrailer(data []byte) bool {
+	line := string(firstLine(data))
+	return line == vectors_trailer || strings.HasPrefix(line, vectors_trailer+" ")
+}
+
+// isBinary reports whether data holds exactly chars rows of a character
+// token, a space, size values of width bytes and a newline, followed by
+// the line of metadata or nothing.
+func isBinary(data []byte, chars, size, width int) bool {
+	p := 0
+	for b := 0; b < chars; b++ {
+		var ok bool
+		_, p, ok = readChar(data, p)
+		p += size*width + 1
+		if !ok || p > len(data) || data[p-1] != '\n' {
+			return false
+		}
+	}
+	return p == len(data) || isTrailer(data[p:])
+}
+
+// parseBinary reads the rows checked by isBinary, and returns the position
+// after them.
+func parseBinary(data []byte, chars, size, width int) ([]string, []float64, int) {
+	vocab := make([]string, chars)
+	M := make([]float64, chars*size)
+	p := 0
+	for b := 0; b < chars; b++ {
+		vocab[b], p, _ = readChar(data, p)
+		for a := 0; a < size; a++ {
+			if width == 4 {
+				M[a+b*size] = float64(math.Float32frombits(binary.LittleEndian.Uint32(data[p:])))
+			} else {
+				M[a+b*size] = math.Float64frombits(binary.LittleEndian.Uint64(data[p:]))
+			}
+			p += width
+		}
+		p++
+	}
+	return vocab, M, p
+}
+
+// parseText reads rows of a character token, a space and size values
+// separated by spaces. The first character always belongs to the token,
+// so that the space, newline and other whitespace characters written by
+// the text format are not mistaken for separators. It returns the
+// position after the rows.
+func parseText(data []byte, chars, size int) ([]string, []float64, int, error) {
+	vocab := make([]string, chars)
+	M := make([]float64, chars*size)
+	p := 0
+	for b := 0; b < chars; b++ {
+		if p >= len(data) {
+			return nil, nil, 0, errors.New("unexpected end of file")
+		}
+		var ok bool
+		vocab[b], p, ok = readChar(data, p)
+		if !ok {
+			return nil, nil, 0, fmt.Errorf("character %d: invalid character", b)
+		}
+		for a := 0; a < size; a++ {
+			for p < len(data) && data[p] == ' ' {
+				p++
+			}
+			q := p
+			for q < len(data) && data[q] != ' ' && data[q] != '\n' {
+				q++
+			}
+			v, err := strconv.ParseFloat(string(data[p:q]), 64)
+			if err != nil {
+				return nil, nil, 0, fmt.Errorf("character %d: %v", b, err)
+			}
+			M[a+b*size] = v
+			p = q
+		}
+		for p < len(data) && data[p] != '\n' {
+			p++
+		}
+		p++
+	}
+	return vocab, M, p, nil
+}
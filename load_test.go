@@ -0,0 +1,98 @@
+package char2vec
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testModel() *Model {
+	vocab := []string{sentence_end, " ", "\n", "a", "あ", "👍🏽"}
+	vectors := make([]float64, len(vocab)*3)
+	for a := range vectors {
+		vectors[a] = float64(a)/8 - 1
+	}
+	m := newModel(vocab, 3, vectors)
+	m.Normalization = NormalizeNFKC
+	m.Unit = UnitGrapheme
+	m.Seed = 7
+	m.Deterministic = true
+	return m
+}
+
+func writeTestVectors(t *testing.T, m *Model, format Format) string {
+	var buf bytes.Buffer
+	if err := m.WriteVectors(&buf, format); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "vectors")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestVectorsMetadata(t *testing.T) {
+	m := testModel()
+	m.Partial = true
+	for _, format := range []Format{FormatText, FormatBinary, FormatWord2Vec} {
+		path := writeTestVectors(t, m, format)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header := strings.Fields(string(firstLine(data))); len(header) != 2 {
+			t.Errorf("format %d: header %q, want two numbers", format, header)
+		}
+		got, err := Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Normalization != m.Normalization || got.Unit != m.Unit || got.Seed != m.Seed || got.Deterministic != m.Deterministic || got.Partial != m.Partial {
+			t.Errorf("format %d: read %q %q %d %v %v", format, got.Normalization, got.Unit, got.Seed, got.Deterministic, got.Partial)
+		}
+	}
+}
+
+func TestLoadDetectsFormat(t *testing.T) {
+	m := testModel()
+	tests := []struct {
+		format    Format
+		tolerance float64
+	}{
+		{FormatText, 1e-6},
+		{FormatBinary, 0},
+		{FormatWord2Vec, 1e-7},
+	}
+	for _, test := range tests {
+		path := writeTestVectors(t, m, test.format)
+		for _, format := range []Format{FormatAuto, test.format} {
+			got, err := LoadFormat(path, format)
+			if err != nil {
+				t.Fatalf("format %d read as %d: %v", test.format, format, err)
+			}
+			if got.Size != m.Size || len(got.Vocab) != len(m.Vocab) {
+				t.Fatalf("format %d read as %d: %d characters of size %d", test.format, format, len(got.Vocab), got.Size)
+			}
+			for a, char := range m.Vocab {
+				if got.Vocab[a] != char || got.Index(char) != a {
+					t.Errorf("format %d read as %d: character %d read as %q, want %q", test.format, format, a, got.Vocab[a], char)
+				}
+			}
+			for a, v := range m.Vectors {
+				if d := got.Vectors[a] - v; d > test.tolerance || d < -test.tolerance {
+					t.Errorf("format %d read as %d: value %d read as %g, want %g", test.format, format, a, got.Vectors[a], v)
+					break
+				}
+			}
+		}
+	}
+	if _, err := LoadFormat(writeTestVectors(t, m, FormatText), FormatBinary); err == nil {
+		t.Error("text vectors read in the binary format")
+	}
+	if _, err := LoadFormat(writeTestVectors(t, m, FormatBinary), FormatWord2Vec); err == nil {
+		t.Error("binary vectors read in the word2vec format")
+	}
+}
@@ -0,0 +1,315 @@
+package char2vec
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Model holds trained character vectors.
+type Model struct {
+	Vocab      []string  // characters or grapheme clusters, in vocabulary order
+	Size       int       // size of character vectors
+	Vectors    []float64 // len(Vocab)*Size values, one row per character
+	Normalized []float64 // Vectors scaled to unit length
+	Partial    bool      // training was interrupted before it finished
+
+	// Normalization is the Unicode normalization applied to the training
+	// text (NormalizeNFC etc.), or "" for none. Queries are normalized
+	// the same way by NormalizeText.
+	Normalization string
+	// Unit is the unit of training, UnitChar or UnitGrapheme; "" means
+	// UnitChar. Tokens splits queries into the same units.
+	Unit string
+	// Seed is the seed of the random numbers of training, or 0 for the
+	// defaults; Deterministic reports whether the training was
+	// reproducible (the -seed and -deterministic options).
+	Seed          int64
+	Deterministic bool
+
+	// Context holds the output vectors (syn1neg) in the same layout as
+	// Vectors, or nil when they are not available.
+	Context           []float64
+	NormalizedContext []float64 // Context scaled to unit length
+
+	// Nodes holds the vectors of the inner nodes of the Huffman tree of
+	// hierarchical softmax (syn1), one row per node, or nil when they are
+	// not available. They are not vectors of characters.
+	Nodes []float64
+
+	index      map[string]int
+	components *Model              // vectors of the components, see Compose
+	comp_table map[string][]string // components of the characters besides their decompositions
+}
+
+func newModel(vocab []string, size int, vectors []float64) *Model {
+	m := &Model{
+		Vocab:      vocab,
+		Size:       size,
+		Vectors:    vectors,
+		Normalized: normalize(vectors, size),
+		index:      make(map[string]int, len(vocab)),
+	}
+	for b := range vocab {
+		if _, ok := m.index[vocab[b]]; !ok {
+			m.index[vocab[b]] = b
+		}
+	}
+	return m
+}
+
+// normalize returns the rows of vectors scaled to unit length.
+func normalize(vectors []float64, size int) []float64 {
+	M := make([]float64, len(vectors))
+	for b := 0; b < len(vectors)/size; b++ {
+		var length float64 = 0
+		for a := 0; a < size; a++ {
+			length += vectors[a+b*size] * vectors[a+b*size]
+		}
+		length = math.Sqrt(length)
+		for a := 0; a < size; a++ {
+			if length != 0 {
+				M[a+b*size] = vectors[a+b*size] / length
+			}
+		}
+	}
+	return M
+}
+
+func (m *Model) setContext(context []float64) {
+	m.Context = context
+	m.NormalizedContext = normalize(context, m.Size)
+}
+
+// LoadContext reads the output vectors written by the -output-context
+// option of the char2vec command into Context. Characters missing from
+// the file get zero vectors.
+func (m *Model) LoadContext(path string) error {
+	c, err := Load(path)
+	if err != nil {
+		return err
+	}
+	if c.Size != m.Size {
+		return fmt.Errorf("%s: size %d does not match size %d", path, c.Size, m.Size)
+	}
+	context := make([]float64, len(m.Vectors))
+	for b, char := range m.Vocab {
+		if i := c.Index(char); i != -1 {
+			copy(context[b*m.Size:(b+1)*m.Size], c.Vectors[i*m.Size:(i+1)*m.Size])
+		}
+	}
+	m.setContext(context)
+	return nil
+}
+
+// ContextModel returns a model whose vectors are the output vectors, or
+// nil when they are not available.
+func (m *Model) ContextModel() *Model {
+	if m.Context == nil {
+		return nil
+	}
+	c := newModel(m.Vocab, m.Size, m.Context)
+	c.Partial = m.Partial
+	c.Normalization = m.Normalization
+	c.Unit = m.Unit
+	c.Seed = m.Seed
+	c.Deterministic = m.Deterministic
+	return c
+}
+
+// NodesModel returns a model whose vectors are the inner-node vectors of
+// hierarchical softmax, named node0, node1... in the order of the nodes,
+// or nil when they are not available.
+func (m *Model) NodesModel() *Model {
+	if m.Nodes == nil {
+		return nil
+	}
+	vocab := make([]string, len(m.Nodes)/m.Size)
+	for a := range vocab {
+		vocab[a] = fmt.Sprintf("node%d", a)
+	}
+	c := newModel(vocab, m.Size, m.Nodes)
+	c.Partial = m.Partial
+	c.Normalization = m.Normalization
+	c.Unit = m.Unit
+	c.Seed = m.Seed
+	c.Deterministic = m.Deterministic
+	return c
+}
+
+// CombinedModel returns a model whose vectors are the average of the
+// input and the output vectors, or nil when the output vectors are not
+// available.
+func (m *Model) CombinedModel() *Model {
+	if m.Context == nil {
+		return nil
+	}
+	vectors := make([]float64, len(m.Vectors))
+	for a := range vectors {
+		vectors[a] = (m.Vectors[a] + m.Context[a]) / 2
+	}
+	c := newModel(m.Vocab, m.Size, vectors)
+	c.Partial = m.Partial
+	c.Normalization = m.Normalization
+	c.Unit = m.Unit
+	c.Seed = m.Seed
+	c.Deterministic = m.Deterministic
+	return c
+}
+
+// NormalizeText returns s normalized like the training text of the model.
+func (m *Model) NormalizeText(s string) string {
+	s, _ = NormalizeString(m.Normalization, s)
+	return s
+}
+
+// SetNormalization sets the normalization of queries to form, for models
+// that do not record theirs. It fails when the model records another one.
+func (m *Model) SetNormalization(form string) error {
+	if _, err := newNormalizer(form); err != nil {
+		return err
+	}
+	if m.Normalization != "" && m.Normalization != form {
+		return fmt.Errorf("the model is normalized with %s, not %s", m.Normalization, form)
+	}
+	m.Normalization = form
+	return nil
+}
+
+// Tokens splits s into the units of the vocabulary.
+func (m *Model) Tokens(s string) []string {
+	return Tokens(m.Unit, s)
+}
+
+// Index returns the position of a character in the vocabulary; if the
+// character is not found, returns -1.
+func (m *Model) Index(char string) int {
+	i, ok := m.index[char]
+	if !ok {
+		return -1
+	}
+	return i
+}
+
+// WriteVectors writes the character vectors in the given format. The
+// word2vec format writes the </s> slot, whitespace and control characters
+// as escaped tokens, so that word2vec readers can split them. The header
+// holds the two numbers that word2vec readers expect; the normalization,
+// the unit and the seed are recorded in a line following the vectors,
+// which word2vec readers do not read, and are restored by Load, as is the
+// mark of a partially trained model.
+func (m *Model) WriteVectors(w io.Writer, format Format) error {
+	fo := bufio.NewWriter(w)
+	layer1_size := m.Size
+	var vec32 []float32 = make([]float32, layer1_size)
+	fmt.Fprintf(fo, "%d %d\n", len(m.Vocab), layer1_size)
+	for a := range m.Vocab {
+		if format == FormatWord2Vec {
+			fmt.Fprintf(fo, "%s ", escapeToken(m.Vocab[a]))
+		} else {
+			fmt.Fprintf(fo, "%s ", textToken(m.Vocab[a]))
+		}
+		switch format {
+		case FormatText:
+			for b := 0; b < layer1_size; b++ {
+				fmt.Fprintf(fo, "%f ", m.Vectors[a*layer1_size+b])
+			}
+		case FormatWord2Vec:
+			for b := 0; b < layer1_size; b++ {
+				vec32[b] = float32(m.Vectors[a*layer1_size+b])
+			}
+			binary.Write(fo, binary.LittleEndian, vec32)
+		default:
+			binary.Write(fo, binary.LittleEndian, m.Vectors[a*layer1_size:(a+1)*layer1_size])
+		}
+		fmt.Fprintf(fo, "\n")
+	}
+	fmt.Fprintf(fo, "%s", vectors_trailer)
+	if m.Normalization != "" {
+		fmt.Fprintf(fo, " normalize=%s", m.Normalization)
+	}
+	if m.Unit == UnitGrapheme {
+		fmt.Fprintf(fo, " unit=%s", m.Unit)
+	}
+	fmt.Fprintf(fo, " seed=%d", m.Seed)
+	if m.Deterministic {
+		fmt.Fprintf(fo, " deterministic=1")
+	}
+	if m.Partial {
+		fmt.Fprintf(fo, " partial=1")
+	}
+	fmt.Fprintf(fo, "\n")
+	return fo.Flush()
+}
+
+// WriteClasses runs K-means on the character vectors and writes the class
+// of each character.
+func (m *Model) WriteClasses(w io.Writer, classes int) error {
+	fo := bufio.NewWriter(w)
+	cl := m.KMeans(classes)
+	for a := range m.Vocab {
+		fmt.Fprintf(fo, "%s %d\n", m.Vocab[a], cl[a])
+	}
+	return fo.Flush()
+}
+
+// KMeans clusters the character vectors into the given number of classes
+// and returns the class of each character.
+func (m *Model) KMeans(classes int) []int {
+	vocab_size := len(m.Vocab)
+	layer1_size := m.Size
+	syn0 := m.Vectors
+	var clcn int = classes
+	var iter int = 10
+	var closeid int
+	var centcn []int = make([]int, classes)
+	var cl []int = make([]int, vocab_size)
+	var closev, x float64
+	var cent []float64 = make([]float64, classes*layer1_size)
+	for a := 0; a < vocab_size; a++ {
+		cl[a] = a % clcn
+	}
+	for a := 0; a < iter; a++ {
+		for b := 0; b < clcn*layer1_size; b++ {
+			cent[b] = 0
+		}
+		for b := 0; b < clcn; b++ {
+			centcn[b] = 1
+		}
+		for c := 0; c < vocab_size; c++ {
+			for d := 0; d < layer1_size; d++ {
+				cent[layer1_size*cl[c]+d] += syn0[c*layer1_size+d]
+			}
+			centcn[cl[c]]++
+		}
+		for b := 0; b < clcn; b++ {
+			closev = 0
+			for c := 0; c < layer1_size; c++ {
+				cent[layer1_size*b+c] /= float64(centcn[b])
+				closev += cent[layer1_size*b+c] * cent[layer1_size*b+c]
+			}
+			closev = math.Sqrt(closev)
+			for c := 0; c < layer1_size; c++ {
+				cent[layer1_size*b+c] /= closev
+			}
+		}
+		for c := 0; c < vocab_size; c++ {
+			closev = -10
+			closeid = 0
+			for d := 0; d < clcn; d++ {
+				x = 0
+				for b := 0; b < layer1_size; b++ {
+					x += cent[layer1_size*d+b] * syn0[c*layer1_size+b]
+				}
+				if x > closev {
+					closev = x
+					closeid = d
+				}
+			}
+			cl[c] = closeid
+		}
+	}
+	return cl
+}
@@ -0,0 +1,150 @@
+package char2vec
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Tokenizations of the training text accepted by the -ngram-mode option.
+const (
+	NGramGreedy  = "greedy"  // the longest n-gram of the vocabulary at each position
+	NGramOverlap = "overlap" // the unit and every n-gram of the vocabulary starting at each position
+)
+
+// ParseNGram parses a comma separated list of n-gram lengths, e.g. "2,3".
+func ParseNGram(s string) ([]int, error) {
+	var lengths []int
+	for _, field := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n < 2 {
+			return nil, fmt.Errorf("invalid n-gram length %q", field)
+		}
+		lengths = append(lengths, n)
+	}
+	return lengths, nil
+}
+
+// FormatNGram returns the n-gram lengths in the format of ParseNGram.
+func FormatNGram(lengths []int) string {
+	fields := make([]string, len(lengths))
+	for a, n := range lengths {
+		fields[a] = strconv.Itoa(n)
+	}
+	return strings.Join(fields, ",")
+}
+
+// ngramMode returns the tokenization into n-grams.
+func (t *Trainer) ngramMode() string {
+	if t.cfg.NGramMode == "" {
+		return NGramGreedy
+	}
+	return t.cfg.NGramMode
+}
+
+// maxNGram returns the length of the longest n-gram of the vocabulary, or
+// 1 without n-grams.
+func (t *Trainer) maxNGram() int {
+	max := 1
+	for _, n := range t.cfg.NGram {
+		if n > max {
+			max = n
+		}
+	}
+	return max
+}
+
+// isNGram reports whether a vocabulary entry is an n-gram of units.
+func (t *Trainer) isNGram(char string) bool {
+	return len(t.cfg.NGram) > 0 && len(Tokens(t.cfg.Unit, char)) > 1
+}
+
+// joinNGram returns the n-gram of units. N-grams do not span sentence
+// breaks or whitespace, so that they can be written as vocabulary entries.
+func joinNGram(units []string) (string, bool) {
+	for _, unit := range units {
+		if unit == sentence_end || strings.IndexFunc(unit, unicode.IsSpace) >= 0 {
+			return "", false
+		}
+	}
+	return strings.Join(units, ""), true
+}
+
+// countNGrams counts the n-grams ending with the last of units.
+func (t *Trainer) countNGrams(cc *charCounts, units []string) {
+	for _, n := range t.cfg.NGram {
+		if n > len(units) {
+			continue
+		}
+		ngram, ok := joinNGram(units[len(units)-n:])
+		if !ok {
+			continue
+		}
+		cc.add(ngram)
+	}
+}
+
+// readNGramIndex reads the next token of the training text tokenized into
+// the units and n-grams of the vocabulary, and returns its index. The
+// reader records how many units the token consumed, so that the progress
+// of training is counted in units as train_chars is.
+func (t *Trainer) readNGramIndex(fin *trainReader) (int, error) {
+	if len(fin.indices) > 0 {
+		i := fin.indices[0]
+		fin.indices = fin.indices[1:]
+		fin.consumed = 0
+		return i, nil
+	}
+	max := t.maxNGram()
+	for len(fin.units) < max {
+		unit, err := fin.ReadToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return -1, err
+		}
+		if t.isSentenceBreak(unit) {
+			unit = sentence_end
+		}
+		fin.units = append(fin.units, unit)
+	}
+	if len(fin.units) == 0 {
+		return -1, io.EOF
+	}
+	if t.ngramMode() == NGramOverlap {
+		for _, n := range t.cfg.NGram {
+			if n > len(fin.units) {
+				continue
+			}
+			if ngram, ok := joinNGram(fin.units[:n]); ok {
+				if i := t.searchVocab(ngram); i != -1 {
+					fin.indices = append(fin.indices, i)
+				}
+			}
+		}
+	} else {
+		best, best_n := -1, 1
+		for _, n := range t.cfg.NGram {
+			if n > len(fin.units) || n <= best_n {
+				continue
+			}
+			if ngram, ok := joinNGram(fin.units[:n]); ok {
+				if i := t.searchVocab(ngram); i != -1 {
+					best, best_n = i, n
+				}
+			}
+		}
+		if best != -1 {
+			fin.units = fin.units[best_n:]
+			fin.consumed = best_n
+			return best, nil
+		}
+	}
+	i := t.searchVocab(fin.units[0])
+	fin.units = fin.units[1:]
+	fin.consumed = 1
+	return i, nil
+}
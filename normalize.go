@@ -0,0 +1,65 @@
+package char2vec
+
+import (
+	"fmt"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Unicode normalizations accepted by the -normalize option.
+const (
+	NormalizeNFC          = "nfc"           // canonical composition
+	NormalizeNFKC         = "nfkc"          // compatibility composition, e.g. full-width to half-width
+	NormalizeNFKCCasefold = "nfkc_casefold" // NFKC followed by case folding
+)
+
+// normalizer converts the text of the training file and of queries to
+// one normal form, so that a character has a single vocabulary entry.
+// A normalizer must not be shared between goroutines.
+type normalizer struct {
+	form norm.Form
+	fold cases.Caser
+	name string
+}
+
+// newNormalizer returns the normalizer of the given name, or nil for "".
+func newNormalizer(name string) (*normalizer, error) {
+	switch name {
+	case "":
+		return nil, nil
+	case NormalizeNFC:
+		return &normalizer{form: norm.NFC, name: name}, nil
+	case NormalizeNFKC:
+		return &normalizer{form: norm.NFKC, name: name}, nil
+	case NormalizeNFKCCasefold:
+		return &normalizer{form: norm.NFKC, fold: cases.Fold(), name: name}, nil
+	}
+	return nil, fmt.Errorf("unknown normalization %q", name)
+}
+
+// String returns s in normal form.
+func (n *normalizer) String(s string) string {
+	s = n.form.String(s)
+	if n.name == NormalizeNFKCCasefold {
+		// Folding may produce characters that compose again
+		s = n.form.String(n.fold.String(s))
+	}
+	return s
+}
+
+// boundaryBefore reports whether the normal form of the text up to char
+// does not depend on char and the characters after it.
+func (n *normalizer) boundaryBefore(char rune) bool {
+	return n.form.PropertiesString(string(char)).BoundaryBefore()
+}
+
+// NormalizeString returns s in the named normal form. It is used by the
+// query tools, so that queries match the vocabulary of the model.
+func NormalizeString(name, s string) (string, error) {
+	n, err := newNormalizer(name)
+	if n == nil {
+		return s, err
+	}
+	return n.String(s), nil
+}
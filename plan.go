@@ -0,0 +1,166 @@
+package char2vec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"time"
+)
+
+const plan_vocab_size int = 10000      // characters of the model trained by the benchmark of Plan at most
+const plan_table_size int = 1e6        // entries of the unigram table of the benchmark
+const plan_benchmark = 3 * time.Second // duration of the benchmark
+
+// Plan is the estimate of the resources of a training run, see
+// Trainer.Plan. Sizes are in bytes.
+type Plan struct {
+	VocabSize    int   // characters of the vocabulary, after min_count
+	TrainChars   int64 // characters of the training data
+	Syn0Size     int64
+	Syn0compSize int64         // vectors of the components of the characters
+	Syn1Size     int64         // with hierarchical softmax
+	Syn1negSize  int64         // with negative sampling
+	TableSize    int64         // unigram table of negative sampling
+	OutputSize   int64         // file written by OutputFile, approximately
+	CharsPerSec  float64       // training throughput of one goroutine, 0 when not measured
+	TrainingTime time.Duration // estimated duration of training, 0 when not measured
+}
+
+// Memory returns the bytes of the weights and the unigram table.
+func (p *Plan) Memory() int64 {
+	return p.Syn0Size + p.Syn0compSize + p.Syn1Size + p.Syn1negSize + p.TableSize
+}
+
+// Plan runs the vocabulary pass and estimates the memory, the size of the
+// output file and the duration of training, without allocating the model
+// or writing SaveVocabFile.
+// The duration is estimated from a short benchmark of one training
+// goroutine on a model of the plan_vocab_size most frequent characters,
+// assuming that the goroutines run in parallel on the available CPUs. It
+// is not measured for the standard input, which cannot be read twice.
+func (t *Trainer) Plan() (*Plan, error) {
+	if t.cfg.ResumeFile != "" {
+		return nil, errors.New("cannot plan a resumed training")
+	}
+	if err := t.initTrainFiles(); err != nil {
+		return nil, err
+	}
+	if err := t.decompressTrainFiles(context.Background()); err != nil {
+		return nil, err
+	}
+	defer t.removeDataFiles()
+	if err := t.loadVocab(context.Background()); err != nil {
+		return nil, err
+	}
+	if t.cfg.ComponentsFile != "" || t.cfg.Decompose != 0 {
+		if err := t.indexComponents(); err != nil {
+			return nil, err
+		}
+	}
+	layer1_size := int64(t.cfg.Size)
+	weights := int64(t.vocab_size) * layer1_size * 4
+	p := &Plan{VocabSize: t.vocab_size, TrainChars: t.train_chars, Syn0Size: weights}
+	p.Syn0compSize = int64(len(t.comp_names)) * layer1_size * 4
+	if t.cfg.HS != 0 {
+		p.Syn1Size = weights
+	}
+	if t.cfg.Negative > 0 {
+		p.Syn1negSize = weights
+		p.TableSize = int64(t.unigram_size) * 4
+	}
+	p.OutputSize = t.outputSize()
+	if t.streaming() {
+		return p, nil
+	}
+	chars_per_sec, err := t.benchmark()
+	if err != nil {
+		return nil, err
+	}
+	p.CharsPerSec = chars_per_sec
+	if chars_per_sec > 0 {
+		threads := t.cfg.Threads
+		if threads > runtime.NumCPU() {
+			threads = runtime.NumCPU()
+		}
+		if t.cfg.Deterministic != 0 {
+			threads = 1
+		}
+		total := float64(t.cfg.Iter) * float64(t.train_chars)
+		p.TrainingTime = time.Duration(total / (chars_per_sec * float64(threads)) * float64(time.Second))
+	}
+	return p, nil
+}
+
+// outputSize returns the approximate size of the vectors or classes file.
+// Values of the text format take about 10 bytes each.
+func (t *Trainer) outputSize() int64 {
+	var size int64 = 60 // header and metadata
+	for a := 0; a < t.vocab_size; a++ {
+		size += int64(len(t.vocab[a].char)) + 2
+		switch {
+		case t.cfg.Classes > 0:
+			size += 4
+		case Format(t.cfg.Binary) == FormatBinary:
+			size += 8 * int64(t.cfg.Size)
+		case Format(t.cfg.Binary) == FormatWord2Vec:
+			size += 4 * int64(t.cfg.Size)
+		default:
+			size += 10 * int64(t.cfg.Size)
+		}
+	}
+	return size
+}
+
+// benchmark trains a copy of the model, reduced to the plan_vocab_size
+// most frequent characters, with one goroutine for plan_benchmark, and
+// returns the characters trained per second.
+func (t *Trainer) benchmark() (float64, error) {
+	cfg := t.cfg
+	cfg.Threads = 1
+	cfg.Iter = 1
+	cfg.CheckpointFile = ""
+	cfg.InitModelFile = ""
+	cfg.Deterministic = 0
+	b := NewTrainer(cfg)
+	b.log = io.Discard
+	b.vocab_size = t.vocab_size
+	if b.vocab_size > plan_vocab_size {
+		b.vocab_size = plan_vocab_size
+	}
+	b.vocab = make(vocab_slice, b.vocab_size+1)
+	for a := 0; a < b.vocab_size; a++ {
+		b.vocab[a] = vocab_char{char: t.vocab[a].char, cn: t.vocab[a].cn, code: make([]byte, MAX_CODE_LENGTH), point: make([]int, MAX_CODE_LENGTH)}
+		b.vocab_hash[b.vocab[a].char] = a
+	}
+	b.train_chars = t.train_chars
+	b.files, b.file_sizes = t.files, t.file_sizes
+	b.thread_parts = [][]filePart{wholeFiles(t.data_files)}
+	b.sched = newSchedule(cfg.Alpha, t.train_chars)
+	b.unigram_size = plan_table_size
+	b.initNet()
+	if err := b.initComponents(); err != nil {
+		return 0, err
+	}
+	if cfg.Negative > 0 {
+		b.initUnigramTable()
+	}
+	b.threads = []threadState{{LocalIter: 1, NextRandom: seedRandom(cfg.Seed, 0)}}
+	b.finished = make([]bool, 1)
+	b.running = 1
+	ctx, cancel := context.WithTimeout(context.Background(), plan_benchmark)
+	defer cancel()
+	b.start = time.Now()
+	err := b.trainModelThread(ctx, 0)
+	elapsed := time.Since(b.start).Seconds()
+	if err != nil && err != context.DeadlineExceeded {
+		return 0, err
+	}
+	chars := b.sched.progress() + b.threads[0].CharCount - b.threads[0].LastCharCount
+	if elapsed <= 0 {
+		return 0, nil
+	}
+	fmt.Fprintf(t.log, "Benchmark: %d characters in %.1f seconds\n", chars, elapsed)
+	return float64(chars) / elapsed, nil
+}
@@ -0,0 +1,78 @@
+package char2vec
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// schedule holds the progress of training and the learning rate, which
+// the training goroutines share. The goroutines report the characters
+// they have trained with advance, and read the learning rate with alpha;
+// both are safe for concurrent use.
+//
+// The progress is the number of characters trained since the start of
+// training, over all the goroutines and iterations, resumed ones
+// included. It only increases; it reaches total, iter*train_chars, when
+// training ends.
+//
+// The learning rate decreases linearly with the progress, as in word2vec:
+// from starting_alpha at 0 to 0 at total, but not below starting_alpha *
+// 0.0001. advance updates it, and it never increases, whatever the order
+// in which concurrent updates complete. A total of 0 means that the
+// length of training is unknown; the learning rate then stays at
+// starting_alpha.
+type schedule struct {
+	chars          int64  // progress, accessed atomically
+	alpha_bits     uint64 // math.Float64bits of the learning rate, accessed atomically
+	starting_alpha float64
+	total          int64
+}
+
+// newSchedule returns the schedule of training total characters, from
+// the learning rate starting_alpha.
+func newSchedule(starting_alpha float64, total int64) *schedule {
+	s := &schedule{starting_alpha: starting_alpha, total: total}
+	s.restore(0, starting_alpha)
+	return s
+}
+
+// restore sets the progress and the learning rate read from a checkpoint.
+// The goroutines must not be running.
+func (s *schedule) restore(chars int64, alpha float64) {
+	atomic.StoreInt64(&s.chars, chars)
+	atomic.StoreUint64(&s.alpha_bits, math.Float64bits(alpha))
+}
+
+// advance adds chars trained characters to the progress, and lowers the
+// learning rate accordingly.
+func (s *schedule) advance(chars int64) {
+	alpha := s.alphaAt(atomic.AddInt64(&s.chars, chars))
+	for {
+		old := atomic.LoadUint64(&s.alpha_bits)
+		if math.Float64frombits(old) <= alpha || atomic.CompareAndSwapUint64(&s.alpha_bits, old, math.Float64bits(alpha)) {
+			return
+		}
+	}
+}
+
+// alphaAt returns the learning rate at a progress of chars characters.
+func (s *schedule) alphaAt(chars int64) float64 {
+	if s.total <= 0 {
+		return s.starting_alpha
+	}
+	alpha := s.starting_alpha * (1 - float64(chars)/float64(s.total+1))
+	if alpha < s.starting_alpha*0.0001 {
+		alpha = s.starting_alpha * 0.0001
+	}
+	return alpha
+}
+
+// alpha returns the current learning rate.
+func (s *schedule) alpha() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&s.alpha_bits))
+}
+
+// progress returns the number of characters trained.
+func (s *schedule) progress() int64 {
+	return atomic.LoadInt64(&s.chars)
+}
@@ -0,0 +1,107 @@
+package char2vec
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestScheduleAlphaAt(t *testing.T) {
+	tests := []struct {
+		total int64
+		chars int64
+		want  float64
+	}{
+		{999, 0, 0.05},
+		{999, 500, 0.025},
+		{999, 900, 0.005},
+		{999, 999, 0.00005},
+		{999, 1000, 0.05 * 0.0001}, // floor
+		{999, 5000, 0.05 * 0.0001},
+		{0, 0, 0.05}, // unknown total
+		{0, 5000, 0.05},
+	}
+	for _, test := range tests {
+		s := newSchedule(0.05, test.total)
+		if got := s.alphaAt(test.chars); !closeTo(got, test.want) {
+			t.Errorf("total %d: alphaAt(%d) = %g, want %g", test.total, test.chars, got, test.want)
+		}
+	}
+}
+
+func TestScheduleAdvance(t *testing.T) {
+	s := newSchedule(0.05, 999)
+	if s.alpha() != 0.05 || s.progress() != 0 {
+		t.Fatalf("new schedule: alpha %g, progress %d", s.alpha(), s.progress())
+	}
+	steps := []struct {
+		chars    int64
+		progress int64
+		alpha    float64
+	}{
+		{100, 100, 0.045},
+		{400, 500, 0.025},
+		{0, 500, 0.025},
+		{2000, 2500, 0.05 * 0.0001},
+	}
+	for _, step := range steps {
+		s.advance(step.chars)
+		if s.progress() != step.progress || !closeTo(s.alpha(), step.alpha) {
+			t.Errorf("advance(%d): progress %d, alpha %g; want %d, %g", step.chars, s.progress(), s.alpha(), step.progress, step.alpha)
+		}
+	}
+}
+
+func TestScheduleRestore(t *testing.T) {
+	s := newSchedule(0.05, 999)
+	s.restore(500, 0.03)
+	if s.progress() != 500 || s.alpha() != 0.03 {
+		t.Fatalf("restore: progress %d, alpha %g", s.progress(), s.alpha())
+	}
+	// The restored alpha is kept until the progress lowers it
+	s.advance(0)
+	if !closeTo(s.alpha(), 0.025) {
+		t.Errorf("advance(0) after restore: alpha %g, want 0.025", s.alpha())
+	}
+	s.restore(100, 0.01)
+	s.advance(100)
+	if s.progress() != 200 || s.alpha() != 0.01 {
+		t.Errorf("advance after restore: progress %d, alpha %g; want 200, 0.01", s.progress(), s.alpha())
+	}
+}
+
+// TestScheduleConcurrent checks, with -race, that concurrent goroutines
+// advance the schedule without losing progress and that alpha never
+// increases.
+func TestScheduleConcurrent(t *testing.T) {
+	const goroutines, steps = 8, 1000
+	s := newSchedule(0.05, goroutines*steps*10)
+	var wg sync.WaitGroup
+	for a := 0; a < goroutines; a++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			last := s.alpha()
+			for b := 0; b < steps; b++ {
+				s.advance(10)
+				alpha := s.alpha()
+				if alpha > last {
+					t.Errorf("alpha increased from %g to %g", last, alpha)
+					return
+				}
+				last = alpha
+			}
+		}()
+	}
+	wg.Wait()
+	if s.progress() != goroutines*steps*10 {
+		t.Errorf("progress %d, want %d", s.progress(), goroutines*steps*10)
+	}
+	if !closeTo(s.alpha(), s.alphaAt(s.progress())) {
+		t.Errorf("final alpha %g, want %g", s.alpha(), s.alphaAt(s.progress()))
+	}
+}
+
+func closeTo(a, b float64) bool {
+	d := a - b
+	return d < 1e-12 && d > -1e-12
+}
@@ -0,0 +1,141 @@
+package char2vec
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// stdin_file is the TrainFile that reads the training text from the
+// standard input.
+const stdin_file string = "-"
+
+const stream_chunk_size int = 1 << 16 // bytes of the text sent to a goroutine at once
+
+// streaming reports whether the training text is read from the standard
+// input. The input is read once: the vocabulary comes from ReadVocabFile,
+// or from the first StreamVocabSize bytes, which are kept in memory and
+// trained on with the rest, in a single iteration.
+//
+// The learning rate decreases over the characters counted in the
+// vocabulary, or over StreamChars when that is set. When the vocabulary
+// comes from the first bytes of a longer input, and StreamChars is not
+// set, the length of the input is unknown, and the learning rate stays at
+// Alpha. Subsampling uses the frequencies of the characters in the
+// vocabulary, which are those of the first bytes then.
+func (t *Trainer) streaming() bool {
+	return len(t.files) == 1 && t.files[0] == stdin_file
+}
+
+// totalChars returns the characters of training for the schedule of the
+// learning rate, or 0 when they are unknown.
+func (t *Trainer) totalChars() int64 {
+	if t.streaming() && t.cfg.StreamChars > 0 {
+		return t.cfg.StreamChars
+	}
+	if t.stream_head {
+		fmt.Fprintf(t.log, "The length of the standard input is unknown, alpha is kept at %f; set -stream-chars to decrease it\n", t.cfg.Alpha)
+		return 0
+	}
+	return int64(t.cfg.Iter) * t.train_chars
+}
+
+// initStream checks the options of training from the standard input.
+func (t *Trainer) initStream() error {
+	if t.cfg.CheckpointFile != "" || t.cfg.ResumeFile != "" {
+		return errors.New("training from the standard input cannot be checkpointed")
+	}
+	if t.cfg.Iter > 1 {
+		fmt.Fprintf(t.log, "Using 1 iteration, the standard input is read once\n")
+		t.cfg.Iter = 1
+	}
+	if t.stdin == nil {
+		r, err := decompress(stdin_file, bufio.NewReader(os.Stdin))
+		if err != nil {
+			return err
+		}
+		t.stdin = r
+	}
+	return nil
+}
+
+// readStdinHead reads the first StreamVocabSize bytes of the standard
+// input, up to the end of their last line, to learn the vocabulary from.
+func (t *Trainer) readStdinHead() ([]byte, error) {
+	head := make([]byte, t.cfg.StreamVocabSize)
+	n, err := io.ReadFull(t.stdin, head)
+	head = head[:n]
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		t.stdin = bytes.NewReader(head)
+		return head, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(t.log, "Learning the vocabulary from the first %d bytes of the standard input\n", n)
+	t.stream_head = true
+	t.stdin = io.MultiReader(bytes.NewReader(head), t.stdin)
+	if i := bytes.LastIndexByte(head, '\n'); i >= 0 {
+		head = head[:i+1]
+	}
+	return head, nil
+}
+
+// streamStdin reads the standard input and fans it out to the training
+// goroutines in chunks ending at line ends, so that sentences are not
+// split across goroutines. It closes t.chunks at the end of the input,
+// after recording a read error in t.stream_err.
+func (t *Trainer) streamStdin(ctx context.Context) {
+	defer close(t.chunks)
+	r := bufio.NewReaderSize(t.stdin, stream_chunk_size)
+	for {
+		chunk := make([]byte, stream_chunk_size)
+		n, err := io.ReadFull(r, chunk)
+		chunk = chunk[:n]
+		if err == nil {
+			var line []byte
+			line, err = r.ReadBytes('\n')
+			chunk = append(chunk, line...)
+		}
+		if len(chunk) > 0 {
+			select {
+			case t.chunks <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return
+		}
+		if err != nil {
+			t.stream_err = err
+			return
+		}
+	}
+}
+
+// chunkReader reads the chunks of streamStdin.
+type chunkReader struct {
+	t     *Trainer
+	chunk []byte
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.chunk) == 0 {
+		chunk, ok := <-r.t.chunks
+		if !ok {
+			if r.t.stream_err != nil {
+				return 0, r.t.stream_err
+			}
+			return 0, io.EOF
+		}
+		r.chunk = chunk
+	}
+	n := copy(p, r.chunk)
+	r.chunk = r.chunk[n:]
+	return n, nil
+}
@@ -0,0 +1,835 @@
+// Package char2vec implements the character level version of word2vec.
+package char2vec
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const EXP_TABLE_SIZE int = 1000
+const MAX_EXP float64 = 6.
+const MAX_SENTENCE_LENGTH int = 1000
+const MAX_CODE_LENGTH int = 40
+
+const SEEK_SET int = 0
+
+const table_size int = 1e8
+
+const max_segment_length int = 32 // characters normalized at once at most
+
+// Trainer owns the vocabulary, the weights and the exp table of one
+// training run. Trainers do not share state, so several of them may run
+// in the same process.
+type Trainer struct {
+	cfg Config
+	log io.Writer
+
+	vocab          vocab_slice
+	vocab_hash     map[string]int
+	sentence_break map[rune]bool // characters read as </s>
+	vocab_max_size int
+	vocab_size     int
+	min_reduce     int64
+	keep           map[string]bool // characters of KeepFile
+	train_chars    int64
+	files          []string     // training files
+	file_sizes     []int64      // sizes of the training files
+	data_files     []string     // training files as read, decompressed
+	data_sizes     []int64      // sizes of data_files
+	thread_parts   [][]filePart // parts of the training files of each goroutine
+	sched          *schedule    // learning rate and progress
+	syn0           []float32
+	syn1           []float32
+	syn1neg        []float32
+	expTable       []float64
+	table          []int32
+	unigram_size   int    // entries of table
+	frozen         []bool // rows not updated during training
+	start          time.Time
+
+	comp_table map[string][]string // components read from ComponentsFile
+	comp_names []string            // components of the vocabulary
+	comps      [][]int             // components of each character
+	syn0comp   []float32           // vectors of the components
+	rare       []string            // characters discarded by min_count or MaxVocab
+
+	stdin       io.Reader   // standard input when TrainFile is "-"
+	chunks      chan []byte // chunks of the standard input, see streamStdin
+	stream_err  error       // error reading the standard input
+	stream_head bool        // the vocabulary was learned from the first bytes of a longer input
+
+	threads    []threadState // position of each training goroutine
+	pausing    int32         // set while a checkpoint is being written
+	pause_mu   sync.Mutex
+	pause_cond *sync.Cond
+	paused     int // training goroutines waiting for the checkpoint
+	running    int // training goroutines not finished yet
+
+	finished []bool // training goroutines finished
+	turn     int    // goroutine training a sentence with Deterministic
+}
+
+// NewTrainer returns a Trainer for the given configuration.
+func NewTrainer(cfg Config) *Trainer {
+	t := &Trainer{
+		cfg:            cfg,
+		log:            cfg.log(),
+		vocab_max_size: 1000,
+		min_reduce:     1,
+		unigram_size:   table_size,
+	}
+	t.pause_cond = sync.NewCond(&t.pause_mu)
+	t.vocab = make([]vocab_char, t.vocab_max_size)
+	t.vocab_hash = map[string]int{}
+	t.sentence_break = sentenceBreaks(cfg.SentenceBreak)
+	t.expTable = make([]float64, EXP_TABLE_SIZE+1)
+	for i := 0; i < EXP_TABLE_SIZE; i++ {
+		t.expTable[i] = math.Exp((float64(i)/float64(EXP_TABLE_SIZE)*2 - 1) * MAX_EXP) // Precompute the exp() table
+		t.expTable[i] = t.expTable[i] / (t.expTable[i] + 1)                            // Precompute f(x) = x / (x + 1)
+	}
+	return t
+}
+
+func (t *Trainer) initUnigramTable() {
+	fmt.Fprintln(t.log, "InitUnigramTable")
+	vocab := t.vocab
+	vocab_size := t.vocab_size
+	var train_chars_pow float64 = 0
+	var d1 float64
+	var power float64 = 0.75
+	table_size := t.unigram_size
+	table := make([]int32, table_size)
+	for a := 0; a < vocab_size; a++ {
+		train_chars_pow += math.Pow(float64(vocab[a].cn), power)
+	}
+	i := 0
+	d1 = math.Pow(float64(vocab[i].cn), power) / train_chars_pow
+	for a := 0; a < table_size; a++ {
+		table[a] = int32(i)
+		if float64(a)/float64(table_size) > d1 {
+			i++
+			d1 += math.Pow(float64(vocab[i].cn), power) / train_chars_pow
+		}
+		if i >= vocab_size {
+			i = vocab_size - 1
+		}
+	}
+	t.table = table
+}
+
+func (t *Trainer) initNet() {
+	fmt.Fprintln(t.log, "InitNet")
+	vocab_size := t.vocab_size
+	layer1_size := t.cfg.Size
+	var next_random uint64 = seedRandom(t.cfg.Seed, 1)
+	t.syn0 = make([]float32, vocab_size*layer1_size)
+	if t.cfg.HS != 0 {
+		t.syn1 = make([]float32, vocab_size*layer1_size)
+	}
+	if t.cfg.Negative > 0 {
+		t.syn1neg = make([]float32, vocab_size*layer1_size)
+	}
+	for a := 0; a < vocab_size; a++ {
+		for b := 0; b < layer1_size; b++ {
+			next_random = next_random*uint64(25214903917) + 11
+			t.syn0[a*layer1_size+b] = float32(((float64(next_random&0xFFFF) / float64(65536)) - 0.5) / float64(layer1_size))
+		}
+	}
+	t.createBinaryTree()
+}
+
+// trainReader reads training files in turn and keeps track of the file
+// and the offset of the next character, so that training can be resumed
+// from it. With a normalizer, the characters are read in normal form one
+// segment at a time, and the offset is that of the end of the last
+// segment read.
+type trainReader struct {
+	*bufio.Reader
+	parts    []filePart // parts of files read in turn
+	file     int        // index of the part being read
+	f        *os.File
+	pos      int64
+	norm     *normalizer
+	out      []rune   // normalized characters not returned yet
+	grapheme bool     // ReadToken reads grapheme clusters
+	pending  string   // characters read ahead by ReadToken
+	units    []string // units read ahead for n-grams
+	indices  []int    // n-grams of the last unit not returned yet
+	consumed int      // units consumed by the last readCharIndex
+}
+
+// newTrainReader returns a trainReader of the given parts of files; open
+// starts reading them.
+func (t *Trainer) newTrainReader(parts []filePart) (*trainReader, error) {
+	n, err := newNormalizer(t.cfg.Normalize)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUnit(t.cfg.Unit); err != nil {
+		return nil, err
+	}
+	if mode := t.ngramMode(); mode != NGramGreedy && mode != NGramOverlap {
+		return nil, fmt.Errorf("unknown n-gram mode %q", mode)
+	}
+	return &trainReader{parts: parts, norm: n, grapheme: t.cfg.Unit == UnitGrapheme}, nil
+}
+
+func (r *trainReader) ReadRune() (rune, int, error) {
+	if r.norm == nil {
+		char, size, err := r.readRaw()
+		r.pos += int64(size)
+		return char, size, err
+	}
+	for len(r.out) == 0 {
+		if err := r.readSegment(); err != nil {
+			return 0, 0, err
+		}
+	}
+	char := r.out[0]
+	r.out = r.out[1:]
+	return char, utf8.RuneLen(char), nil
+}
+
+// readSegment reads the characters up to the next normalization boundary
+// and stores them in normal form.
+func (r *trainReader) readSegment() error {
+	var seg []rune
+	for len(seg) < max_segment_length {
+		char, size, err := r.readRaw()
+		if err != nil {
+			if len(seg) == 0 {
+				return err
+			}
+			break
+		}
+		if size == 0 {
+			// The </s> between two files ends the segment; the next file
+			// is open already, so that it cannot be unread
+			seg = append(seg, char)
+			break
+		}
+		if len(seg) > 0 && r.norm.boundaryBefore(char) {
+			r.Reader.UnreadRune()
+			break
+		}
+		r.pos += int64(size)
+		seg = append(seg, char)
+	}
+	r.out = []rune(r.norm.String(string(seg)))
+	return nil
+}
+
+func (t *Trainer) trainModelThread(ctx context.Context, id int) error {
+	fmt.Fprintln(t.log, "TrainModelThread")
+	vocab := t.vocab
+	vocab_size := t.vocab_size
+	layer1_size := t.cfg.Size
+	window := t.cfg.Window
+	sample := t.cfg.Sample
+	hs := t.cfg.HS
+	negative := t.cfg.Negative
+	cbow := t.cfg.CBOW
+	train_chars := t.train_chars
+	syn0, syn1, syn1neg := t.syn0, t.syn1, t.syn1neg
+	expTable, table := t.expTable, t.table
+	table_size := len(table)
+	frozen := t.frozen
+	comps := t.comps
+	var a, b, d, cw, char, last_char int
+	var sentence_length, sentence_position int = 0, 0
+	var char_count, last_char_count int64 = t.threads[id].CharCount, t.threads[id].LastCharCount
+	var sen []int = make([]int, MAX_SENTENCE_LENGTH+1)
+	var l1, l2, c, target, label int
+	var in []float32 // input vector of skip-gram
+	var local_iter int = t.threads[id].LocalIter
+	var next_random uint64 = t.threads[id].NextRandom
+	var f, g float64
+	var now time.Time
+	var neu1 []float64 = make([]float64, layer1_size)
+	var neu1e []float64 = make([]float64, layer1_size)
+	var avg []float32 = make([]float32, layer1_size) // input vector of skip-gram with components
+	if local_iter == 0 {
+		return nil
+	}
+	br, err := t.prepareTrainFileReader(id, t.threads[id].File, t.threads[id].Pos)
+	if err != nil {
+		return err
+	}
+	defer func() { br.Close() }()
+	held := false // the turn of Deterministic
+	for {
+		if t.cfg.Deterministic != 0 && sentence_length == 0 {
+			t.threads[id] = threadState{File: br.file, Pos: br.pos, LocalIter: local_iter, CharCount: char_count, LastCharCount: last_char_count, NextRandom: next_random}
+			if err := t.waitTurn(ctx, id, held); err != nil {
+				return err
+			}
+			held = true
+		}
+		if char_count-last_char_count > 10000 {
+			t.sched.advance(char_count - last_char_count)
+			last_char_count = char_count
+			if t.cfg.Debug > 1 {
+				now = time.Now()
+				progress := t.sched.progress()
+				if t.sched.total > 0 {
+					fmt.Fprintf(t.log, "%cAlpha: %f  Progress: %.2f%%  Characters/thread/sec: %.2fk  ", 13, t.sched.alpha(),
+						float64(progress)/float64(t.sched.total+1)*100,
+						float64(progress)/(float64(now.Unix()-t.start.Unix()+1)*1000))
+				} else {
+					fmt.Fprintf(t.log, "%cAlpha: %f  Progress: %dK characters  Characters/thread/sec: %.2fk  ", 13, t.sched.alpha(),
+						progress/1000,
+						float64(progress)/(float64(now.Unix()-t.start.Unix()+1)*1000))
+				}
+			}
+		}
+		alpha := t.sched.alpha()
+		var err error
+		if sentence_length == 0 {
+			t.threads[id] = threadState{File: br.file, Pos: br.pos, LocalIter: local_iter, CharCount: char_count, LastCharCount: last_char_count, NextRandom: next_random}
+			if err := t.sentenceBoundary(ctx); err != nil {
+				return err
+			}
+			for {
+				char, err = t.readCharIndex(br)
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					return err
+				}
+				if char == -1 {
+					continue
+				}
+				char_count += int64(br.consumed)
+				if char == 0 {
+					break
+				}
+				// The subsampling randomly discards frequent characters while keeping the ranking same
+				if sample > 0 {
+					var ran float64 = math.Sqrt(float64(vocab[char].cn)/(sample*float64(train_chars))) + 1*(sample*float64(train_chars))/float64(vocab[char].cn)
+					next_random = next_random*25214903917 + 11
+					if ran < float64(next_random&0xFFFF)/65536 {
+						continue
+					}
+				}
+				sen[sentence_length] = char
+				sentence_length++
+				if int(sentence_length) >= MAX_SENTENCE_LENGTH {
+					break
+				}
+			}
+			sentence_position = 0
+		}
+		// A sentence ending the data is trained before the iteration ends
+		if err == io.EOF && sentence_length == 0 {
+			t.sched.advance(char_count - last_char_count)
+			local_iter--
+			if local_iter == 0 {
+				t.threads[id] = threadState{LocalIter: 0}
+				break
+			}
+			char_count = 0
+			last_char_count = 0
+			sentence_length = 0
+			br.Close()
+			br, err = t.prepareTrainFileReader(id, 0, t.threadStart(id))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		char = sen[sentence_position]
+		if char == -1 {
+			continue
+		}
+		for c = 0; c < layer1_size; c++ {
+			neu1[c] = 0
+		}
+		for c = 0; c < layer1_size; c++ {
+			neu1e[c] = 0
+		}
+		next_random = next_random*uint64(25214903917) + 11
+		b = int(next_random % uint64(window))
+		if cbow != 0 { //train the cbow architecture
+			// in -> hidden
+			cw = 0
+			for a = b; a < window*2+1-b; a++ {
+				if a != window {
+					c = sentence_position - window + a
+					if c < 0 {
+						continue
+					}
+					if c >= sentence_length {
+						continue
+					}
+					last_char = sen[c]
+					if last_char == -1 {
+						continue
+					}
+					if comps != nil {
+						t.addInputVector(neu1, last_char)
+					} else {
+						for c = 0; c < layer1_size; c++ {
+							neu1[c] += float64(syn0[c+last_char*layer1_size])
+						}
+					}
+					cw++
+				}
+			}
+			if cw != 0 {
+				for c = 0; c < layer1_size; c++ {
+					neu1[c] /= float64(cw)
+				}
+				if hs != 0 {
+					for d = 0; d < int(vocab[char].codelen); d++ {
+						f = 0
+						l2 = vocab[char].point[d] * layer1_size
+						// Propagate hidden -> output
+						for c = 0; c < layer1_size; c++ {
+							f += neu1[c] * float64(syn1[c+l2])
+						}
+						if f <= -MAX_EXP {
+							continue
+						} else if f >= MAX_EXP {
+							continue
+						} else {
+							f = expTable[(int)((f+MAX_EXP)*(float64(EXP_TABLE_SIZE)/MAX_EXP/2))]
+						}
+						// 'g' is the gradient multiplied by the learning rate
+						g = (1 - float64(vocab[char].code[d]) - f) * alpha
+						// Propagate errors output -> hidden
+						for c = 0; c < layer1_size; c++ {
+							neu1e[c] += g * float64(syn1[c+l2])
+						}
+						// Learn weights hidden -> output
+						for c = 0; c < layer1_size; c++ {
+							syn1[c+l2] += float32(g * neu1[c])
+						}
+					}
+				}
+				// NEGATIVE SAMPLING
+				if negative > 0 {
+					for d = 0; d < negative+1; d++ {
+						if d == 0 {
+							target = char
+							label = 1
+						} else {
+							next_random = next_random*uint64(25214903917) + 11
+							target = int(table[(next_random>>16)%uint64(table_size)])
+							if target == 0 {
+								target = int(next_random%uint64(vocab_size-1)) + 1
+							}
+							if target == char {
+								continue
+							}
+							label = 0
+						}
+						l2 = target * layer1_size
+						f = 0
+						for c = 0; c < layer1_size; c++ {
+							f += neu1[c] * float64(syn1neg[c+l2])
+						}
+						if f > MAX_EXP {
+							g = float64(label-1) * alpha
+						} else if f < -MAX_EXP {
+							g = float64(label-0) * alpha
+						} else {
+							g = (float64(label) - expTable[(int)((f+MAX_EXP)*(float64(EXP_TABLE_SIZE)/MAX_EXP/2))]) * alpha
+						}
+						for c = 0; c < layer1_size; c++ {
+							neu1e[c] += g * float64(syn1neg[c+l2])
+						}
+						if frozen == nil || !frozen[target] {
+							for c = 0; c < layer1_size; c++ {
+								syn1neg[c+l2] += float32(g * neu1[c])
+							}
+						}
+					}
+				}
+				// hidden -> in
+				for a = b; a < window*2+1-b; a++ {
+					if a != window {
+						c = sentence_position - window + a
+						if c < 0 {
+							continue
+						}
+						if c >= sentence_length {
+							continue
+						}
+						last_char = sen[c]
+						if last_char == -1 {
+							continue
+						}
+						if frozen != nil && frozen[last_char] {
+							continue
+						}
+						if comps != nil {
+							t.learnInput(last_char, neu1e)
+							continue
+						}
+						for c = 0; c < layer1_size; c++ {
+							syn0[c+last_char*layer1_size] += float32(neu1e[c])
+						}
+					}
+				}
+			}
+		} else { //train skip-gram
+			for a = b; a < window*2+1-b; a++ {
+				if a != window {
+					c = sentence_position - window + a
+					if c < 0 {
+						continue
+					}
+					if c >= sentence_length {
+						continue
+					}
+					last_char = sen[c]
+					if last_char == -1 {
+						continue
+					}
+					l1 = last_char * layer1_size
+					in = syn0
+					if comps != nil {
+						// Use the average of the character and its components
+						for c = 0; c < layer1_size; c++ {
+							neu1[c] = 0
+						}
+						t.addInputVector(neu1, last_char)
+						for c = 0; c < layer1_size; c++ {
+							avg[c] = float32(neu1[c])
+						}
+						in, l1 = avg, 0
+					}
+					for c = 0; c < layer1_size; c++ {
+						neu1e[c] = 0
+					}
+					// HIERARCHICAL SOFTMAX
+					if hs != 0 {
+						for d = 0; d < int(vocab[char].codelen); d++ {
+							f = 0
+							l2 = vocab[char].point[d] * layer1_size
+							// Propagate hidden -> output
+							for c = 0; c < layer1_size; c++ {
+								f += float64(in[c+l1] * syn1[c+l2])
+							}
+							if f <= -MAX_EXP {
+								continue
+							} else if f >= MAX_EXP {
+								continue
+							} else {
+								f = expTable[(int)((f+MAX_EXP)*(float64(EXP_TABLE_SIZE)/MAX_EXP/2))]
+							}
+							// 'g' is the gradient multiplied by the learning rate
+							g = (1 - float64(vocab[char].code[d]) - f) * alpha
+							// Propagate errors output -> hidden
+							for c = 0; c < layer1_size; c++ {
+								neu1e[c] += g * float64(syn1[c+l2])
+							}
+							// Learn weights hidden -> output
+							for c = 0; c < layer1_size; c++ {
+								syn1[c+l2] += float32(g) * in[c+l1]
+							}
+						}
+					}
+					// NEGATIVE SAMPLING
+					if negative > 0 {
+						for d = 0; d < negative+1; d++ {
+							if d == 0 {
+								target = char
+								label = 1
+							} else {
+								next_random = next_random*uint64(25214903917) + 11
+								target = int(table[(next_random>>16)%uint64(table_size)])
+								if target == 0 {
+									target = int(next_random%uint64(vocab_size-1)) + 1
+								}
+								if target == char {
+									continue
+								}
+								label = 0
+							}
+							l2 = target * layer1_size
+							f = 0
+							for c = 0; c < layer1_size; c++ {
+								f += float64(in[c+l1] * syn1neg[c+l2])
+							}
+							if f > MAX_EXP {
+								g = float64(label-1) * alpha
+							} else if f < -MAX_EXP {
+								g = float64(label-0) * alpha
+							} else {
+								g = (float64(label) - expTable[(int)((f+MAX_EXP)*(float64(EXP_TABLE_SIZE)/MAX_EXP/2))]) * alpha
+							}
+							for c = 0; c < layer1_size; c++ {
+								neu1e[c] += g * float64(syn1neg[c+l2])
+							}
+							if frozen == nil || !frozen[target] {
+								for c = 0; c < layer1_size; c++ {
+									syn1neg[c+l2] += float32(g) * in[c+l1]
+								}
+							}
+						}
+					}
+					// Learn weights input -> hidden
+					if comps != nil && (frozen == nil || !frozen[last_char]) {
+						t.learnInput(last_char, neu1e)
+					} else if frozen == nil || !frozen[last_char] {
+						for c = 0; c < layer1_size; c++ {
+							syn0[c+l1] += float32(neu1e[c])
+						}
+					}
+				}
+			}
+		}
+		sentence_position++
+		if sentence_position >= sentence_length {
+			sentence_length = 0
+			continue
+		}
+	}
+	return nil
+}
+
+// BuildVocab reads the vocabulary from ReadVocabFile or learns it from
+// TrainFile, and saves it to SaveVocabFile when that is set. Train builds
+// the vocabulary itself when it has not been built yet. When ctx is
+// cancelled, BuildVocab stops and returns the context's error.
+func (t *Trainer) BuildVocab(ctx context.Context) error {
+	if err := t.initTrainFiles(); err != nil {
+		return err
+	}
+	if err := t.decompressTrainFiles(ctx); err != nil {
+		return err
+	}
+	defer t.removeDataFiles()
+	return t.buildVocab(ctx)
+}
+
+// buildVocab reads or learns the vocabulary of the data files, and saves
+// it to SaveVocabFile when that is set.
+func (t *Trainer) buildVocab(ctx context.Context) error {
+	if err := t.loadVocab(ctx); err != nil {
+		return err
+	}
+	if t.cfg.SaveVocabFile != "" {
+		return t.saveVocab()
+	}
+	return nil
+}
+
+// loadVocab reads or learns the vocabulary of the data files, without
+// saving it.
+func (t *Trainer) loadVocab(ctx context.Context) error {
+	var err error
+	if t.cfg.KeepFile != "" {
+		if t.keep, err = readKeepFile(t.cfg.KeepFile); err != nil {
+			return err
+		}
+	}
+	if t.cfg.ReadVocabFile != "" {
+		err = t.readVocab()
+	} else {
+		err = t.learnVocabFromTrainFile(ctx)
+	}
+	return err
+}
+
+// Train trains the character vectors and returns the resulting model.
+// When CheckpointFile is set, the training state is written to it
+// periodically; training continues from ResumeFile when that is set.
+//
+// When ctx is cancelled, the training goroutines stop at the end of their
+// current sentence, and Train returns the partially trained model together
+// with the context's error. The training state is then written to
+// CheckpointFile when that is set. When ctx is cancelled before training
+// starts, Train returns no model and the context's error.
+func (t *Trainer) Train(parent context.Context) (*Model, error) {
+	fmt.Fprintln(t.log, "TrainModel")
+	if t.cfg.TrainFile == "" && t.cfg.TrainListFile != "" {
+		fmt.Fprintf(t.log, "Starting training using the files of %s\n", t.cfg.TrainListFile)
+	} else {
+		fmt.Fprintf(t.log, "Starting training using file %s\n", t.cfg.TrainFile)
+	}
+	if t.cfg.ResumeFile != "" {
+		if err := t.readCheckpoint(); err != nil {
+			return nil, err
+		}
+	} else if err := t.initTrainFiles(); err != nil {
+		return nil, err
+	}
+	if err := t.decompressTrainFiles(parent); err != nil {
+		return nil, err
+	}
+	defer t.removeDataFiles()
+	if t.cfg.ResumeFile == "" {
+		if t.vocab_size == 0 {
+			if err := t.buildVocab(parent); err != nil {
+				return nil, err
+			}
+		}
+		t.sched = newSchedule(t.cfg.Alpha, t.totalChars())
+		if t.cfg.InitModelFile != "" {
+			if err := t.initFromModel(); err != nil {
+				return nil, err
+			}
+		} else {
+			t.initNet()
+		}
+		if err := t.initComponents(); err != nil {
+			return nil, err
+		}
+	}
+	if err := t.splitTrainFiles(); err != nil {
+		return nil, err
+	}
+	if t.cfg.ResumeFile == "" {
+		t.threads = make([]threadState, t.cfg.Threads)
+		t.turn = 0
+		for a := 0; a < t.cfg.Threads; a++ {
+			t.threads[a] = threadState{Pos: t.threadStart(a), LocalIter: t.cfg.Iter, NextRandom: seedRandom(t.cfg.Seed, uint64(a))}
+		}
+	}
+	if t.cfg.Negative > 0 {
+		t.initUnigramTable()
+	}
+	if t.cfg.Debug > 0 {
+		t.reportMemory()
+	}
+	if err := parent.Err(); err != nil {
+		return nil, err
+	}
+	t.start = time.Now()
+	t.running = t.cfg.Threads
+	t.finished = make([]bool, t.cfg.Threads)
+	// A failing goroutine stops the others
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	if t.streaming() {
+		t.chunks = make(chan []byte, t.cfg.Threads)
+		go t.streamStdin(ctx)
+	}
+	ch := make(chan error, t.cfg.Threads)
+	for a := 0; a < t.cfg.Threads; a++ {
+		go func(a int) {
+			err := t.trainModelThread(ctx, a)
+			t.threadDone(a, err)
+			ch <- err
+		}(a)
+	}
+	var tick <-chan time.Time
+	if t.cfg.CheckpointFile != "" {
+		ticker := time.NewTicker(t.checkpointTick())
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	next_checkpoint := t.sched.progress() + t.cfg.CheckpointChars
+	var err error
+	for a := 0; a < t.cfg.Threads; {
+		select {
+		case e := <-ch:
+			if e != nil && err == nil {
+				err = e
+				cancel()
+			}
+			a++
+		case <-tick:
+			if t.sched.progress() < next_checkpoint {
+				continue
+			}
+			if e := t.saveCheckpoint(); e != nil {
+				fmt.Fprintf(t.log, "Cannot write checkpoint: %v\n", e)
+			}
+			next_checkpoint = t.sched.progress() + t.cfg.CheckpointChars
+		}
+	}
+	if parent.Err() != nil {
+		if t.cfg.CheckpointFile != "" {
+			if e := t.writeCheckpoint(t.cfg.CheckpointFile); e != nil {
+				fmt.Fprintf(t.log, "Cannot write checkpoint: %v\n", e)
+			}
+		}
+		m := t.model()
+		m.Partial = true
+		return m, parent.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t.model(), nil
+}
+
+// model returns the trained character vectors, copies of syn0 and syn1neg
+// in float64 unless the characters have components; the characters then
+// get the averages of their own and their components' vectors, and
+// characters missing from the vocabulary are added with the averages of
+// their components, with zero context vectors.
+func (t *Trainer) model() *Model {
+	vocab := make([]string, t.vocab_size)
+	for a := 0; a < t.vocab_size; a++ {
+		vocab[a] = t.vocab[a].char
+	}
+	vectors, context := float64s(t.syn0), float64s(t.syn1neg)
+	if t.comps != nil {
+		vocab, vectors = t.componentVectors()
+		if context != nil {
+			context = append(context[:len(context):len(context)], make([]float64, len(vectors)-len(context))...)
+		}
+	}
+	m := newModel(vocab, t.cfg.Size, vectors)
+	m.Normalization = t.cfg.Normalize
+	m.Unit = t.cfg.Unit
+	m.Seed = t.cfg.Seed
+	m.Deterministic = t.cfg.Deterministic != 0
+	if context != nil {
+		m.setContext(context)
+	}
+	if t.comps != nil {
+		m.components = newModel(t.comp_names, t.cfg.Size, float64s(t.syn0comp))
+		m.comp_table = t.comp_table
+	}
+	if t.syn1 != nil && t.vocab_size > 1 {
+		// The tree of vocab_size leaves has vocab_size-1 inner nodes
+		m.Nodes = float64s(t.syn1[:(t.vocab_size-1)*t.cfg.Size])
+	}
+	return m
+}
+
+// reportMemory prints the memory used by the weights, stored as float32,
+// and by the int32 unigram table.
+func (t *Trainer) reportMemory() {
+	const mb = 1 << 20
+	weights := 4 * (len(t.syn0) + len(t.syn1) + len(t.syn1neg) + len(t.syn0comp))
+	table := 4 * len(t.table)
+	fmt.Fprintf(t.log, "Memory: %.1f MB of weights, %.1f MB of unigram table\n", float64(weights)/mb, float64(table)/mb)
+}
+
+// float64s returns the float64 values of v, or nil when v is nil.
+func float64s(v []float32) []float64 {
+	if v == nil {
+		return nil
+	}
+	w := make([]float64, len(v))
+	for a := range v {
+		w[a] = float64(v[a])
+	}
+	return w
+}
+
+// float32s returns the float32 values of v, or nil when v is nil.
+func float32s(v []float64) []float32 {
+	if v == nil {
+		return nil
+	}
+	w := make([]float32, len(v))
+	for a := range v {
+		w[a] = float32(v[a])
+	}
+	return w
+}
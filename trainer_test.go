@@ -0,0 +1,75 @@
+package char2vec
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadRuneFileBoundary(t *testing.T) {
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")}
+	for i, text := range []string{"ｱｲ", "ｳ"} {
+		if err := os.WriteFile(paths[i], []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, normalize := range []string{"", NormalizeNFKC} {
+		tr := NewTrainer(Config{Normalize: normalize})
+		r, err := tr.newTrainReader(wholeFiles(paths))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.open(0, 0); err != nil {
+			t.Fatal(err)
+		}
+		var got []rune
+		for {
+			char, _, err := r.ReadRune()
+			if err != nil {
+				break
+			}
+			got = append(got, char)
+		}
+		r.Close()
+		want := "ｱｲ\x00ｳ"
+		if normalize != "" {
+			want = "アイ\x00ウ"
+		}
+		if string(got) != want {
+			t.Errorf("normalize %q: read %q, want %q", normalize, string(got), want)
+		}
+	}
+}
+
+func TestReadCharIndexError(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(path, []byte("ab"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// The second part cannot be opened
+	parts := wholeFiles([]string{path, filepath.Join(dir, "missing.txt")})
+	for _, ngram := range [][]int{nil, {2}} {
+		tr := NewTrainer(Config{NGram: ngram, Log: io.Discard})
+		r, err := tr.newTrainReader(parts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.open(0, 0); err != nil {
+			t.Fatal(err)
+		}
+		for a := 0; ; a++ {
+			_, err := tr.readCharIndex(r)
+			if err == io.EOF || a == 10 {
+				t.Errorf("ngram %v: no error after %d characters", ngram, a)
+				break
+			}
+			if err != nil {
+				break
+			}
+		}
+		r.Close()
+	}
+}
@@ -0,0 +1,77 @@
+package char2vec
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rivo/uniseg"
+)
+
+// Units of training accepted by the -unit option.
+const (
+	UnitChar     = "char"     // Unicode code points
+	UnitGrapheme = "grapheme" // extended grapheme clusters (UAX #29)
+)
+
+const max_token_length int = 64 // bytes of a grapheme cluster at most
+
+// sentence_end is the vocabulary entry of the </s> slot.
+const sentence_end string = "\x00"
+
+func checkUnit(unit string) error {
+	if unit != "" && unit != UnitChar && unit != UnitGrapheme {
+		return fmt.Errorf("unknown unit %q", unit)
+	}
+	return nil
+}
+
+// Tokens splits s into the units of training: characters, or grapheme
+// clusters for UnitGrapheme.
+func Tokens(unit, s string) []string {
+	var tokens []string
+	if unit != UnitGrapheme {
+		for _, char := range s {
+			tokens = append(tokens, string(char))
+		}
+		return tokens
+	}
+	state := -1
+	for s != "" {
+		var cluster string
+		cluster, s, _, state = uniseg.FirstGraphemeClusterInString(s, state)
+		tokens = append(tokens, cluster)
+	}
+	return tokens
+}
+
+// ReadToken reads the next unit of training. Grapheme clusters are read
+// one character ahead, as a cluster ends where the next one starts; a
+// line feed always ends a cluster, so that a line is read to its end only.
+func (r *trainReader) ReadToken() (string, error) {
+	if !r.grapheme {
+		char, _, err := r.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		return string(char), nil
+	}
+	for {
+		if r.pending != "" {
+			cluster, rest, _, _ := uniseg.FirstGraphemeClusterInString(r.pending, -1)
+			if rest != "" || strings.HasSuffix(cluster, "\n") || len(cluster) >= max_token_length {
+				r.pending = rest
+				return cluster, nil
+			}
+		}
+		char, _, err := r.ReadRune()
+		if err != nil {
+			if r.pending == "" {
+				return "", err
+			}
+			cluster, rest, _, _ := uniseg.FirstGraphemeClusterInString(r.pending, -1)
+			r.pending = rest
+			return cluster, nil
+		}
+		r.pending += string(char)
+	}
+}
@@ -0,0 +1,551 @@
+package char2vec
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+const vocab_hash_size int = 30000000 // Maximum 30 * 0.7 = 21M characters in the vocabulary
+
+const vocab_header string = "#char2vec vocab 1" // First line of a vocabulary file
+
+type vocab_char struct {
+	cn      int64
+	point   []int
+	char    string // a character, or a grapheme cluster with UnitGrapheme
+	code    []byte
+	codelen byte
+}
+
+type vocab_slice []vocab_char
+
+func (me vocab_slice) Len() int {
+	return len(me)
+}
+
+func (me vocab_slice) Less(i, j int) bool {
+	return me[i].cn > me[j].cn
+}
+
+func (me vocab_slice) Swap(i, j int) {
+	tmp := me[i]
+	me[i] = me[j]
+	me[j] = tmp
+}
+
+// Returns position of a character in the vocabulary; if the character is not found, returns -1
+func (t *Trainer) searchVocab(char string) int {
+	i, ok := t.vocab_hash[char]
+	if !ok {
+		return -1
+	}
+	return i
+}
+
+// sentenceBreaks returns the set of the characters of s.
+func sentenceBreaks(s string) map[rune]bool {
+	m := map[rune]bool{}
+	for _, char := range s {
+		m[char] = true
+	}
+	return m
+}
+
+// isSentenceBreak reports whether a token contains a sentence break character.
+func (t *Trainer) isSentenceBreak(token string) bool {
+	for _, char := range token {
+		if t.sentence_break[char] {
+			return true
+		}
+	}
+	return false
+}
+
+// Reads a character and returns its index in the vocabulary; sentence
+// break characters return the index of </s>
+func (t *Trainer) readCharIndex(fin *trainReader) (int, error) {
+	if len(t.cfg.NGram) > 0 {
+		return t.readNGramIndex(fin)
+	}
+	fin.consumed = 1
+	char, err := fin.ReadToken()
+	if err != nil {
+		return -1, err
+	}
+	if t.isSentenceBreak(char) {
+		return 0, nil
+	}
+	return t.searchVocab(char), nil
+}
+
+// Adds a character to the vocabulary
+func (t *Trainer) addCharToVocab(char string) int {
+	t.vocab[t.vocab_size].char = char
+	t.vocab[t.vocab_size].cn = 0
+	t.vocab_size++
+	// Reallocate memory if needed
+	if t.vocab_size+2 >= t.vocab_max_size {
+		t.vocab_max_size += 1000
+		t.vocab = append(t.vocab, make([]vocab_char, 1000)...)
+	}
+	t.vocab_hash[char] = t.vocab_size - 1
+	return t.vocab_size - 1
+}
+
+// Sorts the vocabulary by frequency using character counts. Characters
+// beyond the MaxVocab most frequent ones are discarded as those below
+// min_count; the characters of KeepFile are kept in any case.
+func (t *Trainer) sortVocab() {
+	fmt.Fprintln(t.log, "SortVocab")
+	// Sort the vocabulary and keep </s> at the first position
+	sort.Sort(t.vocab[1:])
+	t.vocab_hash = map[string]int{}
+	size := t.vocab_size
+	t.train_chars = 0
+	b := 0
+	rank := 0 // characters reaching min_count so far
+	for a := 0; a < size; a++ {
+		// Characters occuring less than min_count times will be discarded from the vocab
+		ngram := t.isNGram(t.vocab[a].char)
+		min_count := t.cfg.MinCount
+		if ngram {
+			min_count = t.cfg.NGramMinCount
+		}
+		frequent := t.vocab[a].cn >= min_count
+		if frequent && !ngram && a != 0 {
+			// MaxVocab limits the characters, not the n-grams
+			frequent = t.cfg.MaxVocab <= 0 || rank < t.cfg.MaxVocab
+			rank++
+		}
+		if !frequent && (a != 0) && !t.keep[t.vocab[a].char] {
+			t.vocab_size--
+			if !ngram && (t.cfg.ComponentsFile != "" || t.cfg.Decompose != 0) {
+				// Characters with components get vectors from them
+				t.rare = append(t.rare, t.vocab[a].char)
+			}
+		} else {
+			// Kept entries are moved up, as discarded n-grams may precede
+			// less frequent characters
+			t.vocab[b] = t.vocab[a]
+			// Hash will be re-computed, as after the sorting it is not actual
+			t.vocab_hash[t.vocab[b].char] = b
+			// train_chars counts units; n-grams overlap them
+			if !ngram {
+				t.train_chars += int64(t.vocab[b].cn)
+			}
+			b++
+		}
+	}
+	t.vocab = t.vocab[:t.vocab_size+1]
+	// Allocate memory for the binary tree construction
+	for a := 0; a < t.vocab_size; a++ {
+		t.vocab[a].code = make([]byte, MAX_CODE_LENGTH)
+		t.vocab[a].point = make([]int, MAX_CODE_LENGTH)
+	}
+}
+
+// Reduces the vocabulary by removing infrequent tokens
+func (t *Trainer) reduceVocab() {
+	fmt.Fprintln(t.log, "ReduceVocab")
+	var b int = 0
+	for a := 0; a < t.vocab_size; a++ {
+		if t.vocab[a].cn > t.min_reduce || t.keep[t.vocab[a].char] {
+			t.vocab[b].cn = t.vocab[a].cn
+			t.vocab[b].char = t.vocab[a].char
+			b++
+		} else {
+			t.vocab[a].char = ""
+		}
+	}
+	t.vocab_size = b
+	t.vocab_hash = map[string]int{}
+	for a := 0; a < t.vocab_size; a++ {
+		// Hash will be re-computed, as it is not actual
+		t.vocab_hash[t.vocab[a].char] = a
+	}
+	t.min_reduce++
+}
+
+// readKeepFile reads the characters of a KeepFile, separated by
+// whitespace. Tokens may be escaped as in vocabulary files; lines starting
+// with # are ignored.
+func readKeepFile(file string) (map[string]bool, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	keep := map[string]bool{}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		for _, field := range fields {
+			token, ok := unescapeToken(field)
+			if !ok {
+				return nil, fmt.Errorf("%s:%d: invalid character %q", file, line, field)
+			}
+			keep[token] = true
+		}
+	}
+	return keep, scanner.Err()
+}
+
+// Create binary Huffman tree using the character counts
+// Frequent characters will have short uniqe binary codes
+func (t *Trainer) createBinaryTree() {
+	fmt.Fprintln(t.log, "CreateBinaryTree")
+	vocab := t.vocab
+	vocab_size := t.vocab_size
+	var min1i, min2i, pos1, pos2 int
+	var point []int = make([]int, MAX_CODE_LENGTH)
+	var code []byte = make([]byte, MAX_CODE_LENGTH)
+	var count []int64 = make([]int64, vocab_size*2+1)
+	var binaryt []int = make([]int, vocab_size*2+1)
+	var parent_node []int = make([]int, vocab_size*2+1)
+	for a := 0; a < vocab_size; a++ {
+		count[a] = int64(vocab[a].cn)
+	}
+	for a := vocab_size; a < vocab_size*2; a++ {
+		count[a] = 1e15
+	}
+	pos1 = vocab_size - 1
+	pos2 = vocab_size
+	// Following algorithm constructs the Huffman tree by adding one node at a time
+	for a := 0; a < vocab_size-1; a++ {
+		// First, find two smallest nodes 'min1, min2'
+		if pos1 >= 0 {
+			if count[pos1] < count[pos2] {
+				min1i = pos1
+				pos1--
+			} else {
+				min1i = pos2
+				pos2++
+			}
+		} else {
+			min1i = pos2
+			pos2++
+		}
+		if pos1 >= 0 {
+			if count[pos1] < count[pos2] {
+				min2i = pos1
+				pos1--
+			} else {
+				min2i = pos2
+				pos2++
+			}
+		} else {
+			min2i = pos2
+			pos2++
+		}
+		count[vocab_size+a] = count[min1i] + count[min2i]
+		parent_node[min1i] = vocab_size + a
+		parent_node[min2i] = vocab_size + a
+		binaryt[min2i] = 1
+	}
+	// Now assign binary code to each vocabulary character
+	for a := 0; a < vocab_size; a++ {
+		b := a
+		i := 0
+		for {
+			code[i] = byte(binaryt[b])
+			point[i] = b
+			i++
+			b = parent_node[b]
+			if b == vocab_size*2-2 {
+				break
+			}
+		}
+		vocab[a].codelen = byte(i)
+		vocab[a].point[0] = vocab_size - 2
+		for b = 0; b < i; b++ {
+			vocab[a].code[i-b-1] = code[b]
+			vocab[a].point[i-b] = point[b] - vocab_size
+		}
+	}
+}
+
+// charCounts holds the counts of the characters of one training file, in
+// the order of their first occurrence.
+type charCounts struct {
+	chars  []string
+	counts map[string]int64
+}
+
+func (cc *charCounts) add(char string) {
+	if _, ok := cc.counts[char]; !ok {
+		cc.chars = append(cc.chars, char)
+	}
+	cc.counts[char]++
+}
+
+// countFile counts the characters and the n-grams of the i-th training
+// file, read from its data file.
+func (t *Trainer) countFile(ctx context.Context, i int) (*charCounts, error) {
+	path := t.data_files[i]
+	fin, err := t.newTrainReader(wholeFiles([]string{path}))
+	if err != nil {
+		return nil, err
+	}
+	defer fin.Close()
+	if path == stdin_file {
+		head, err := t.readStdinHead()
+		if err != nil {
+			return nil, err
+		}
+		fin.Reader = bufio.NewReader(bytes.NewReader(head))
+	} else if err := fin.open(0, 0); err != nil {
+		return nil, err
+	}
+	cc := &charCounts{counts: map[string]int64{}}
+	var units []string // the last units, for counting n-grams
+	for {
+		char, err := fin.ReadToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", t.files[i], err)
+		}
+		if train_chars := atomic.AddInt64(&t.train_chars, 1); train_chars%1000000 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if t.cfg.Debug > 1 {
+				fmt.Fprintf(t.log, "%dK%c", train_chars/1000, 13)
+			}
+		}
+		if t.isSentenceBreak(char) {
+			// Sentence breaks are counted as </s>
+			char = sentence_end
+		}
+		cc.add(char)
+		if len(t.cfg.NGram) > 0 {
+			if len(units) == t.maxNGram() {
+				units = units[1:]
+			}
+			units = append(units, char)
+			t.countNGrams(cc, units)
+		}
+	}
+	return cc, nil
+}
+
+// learnVocabFromTrainFile counts the characters of the training files,
+// several files in parallel, and merges the counts in the order of the
+// files, so that the vocabulary does not depend on the scheduling. It
+// returns the context's error when ctx is cancelled.
+func (t *Trainer) learnVocabFromTrainFile(ctx context.Context) error {
+	fmt.Fprintln(t.log, "LearnVocabFromTrainFile")
+	var i int
+	t.vocab_hash = map[string]int{}
+	t.train_chars = 0
+	done := make([]chan *charCounts, len(t.files))
+	errs := make([]error, len(t.files))
+	for a := range done {
+		done[a] = make(chan *charCounts, 1)
+	}
+	var next int64 = -1
+	for a := 0; a < t.cfg.Threads && a < len(t.files); a++ {
+		go func() {
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= len(t.files) {
+					return
+				}
+				var cc *charCounts
+				cc, errs[i] = t.countFile(ctx, i)
+				done[i] <- cc
+			}
+		}()
+	}
+	t.vocab_size = 0
+	t.addCharToVocab(sentence_end)
+	for a := range done {
+		cc := <-done[a]
+		if errs[a] != nil {
+			return errs[a]
+		}
+		for _, char := range cc.chars {
+			i = t.searchVocab(char)
+			if i == -1 {
+				i = t.addCharToVocab(char)
+			}
+			t.vocab[i].cn += cc.counts[char]
+			if float64(t.vocab_size) > float64(vocab_hash_size)*0.7 {
+				t.reduceVocab()
+			}
+		}
+	}
+	t.sortVocab()
+	if t.cfg.Debug > 0 {
+		fmt.Fprintf(t.log, "Vocab size: %d\n", t.vocab_size)
+		fmt.Fprintf(t.log, "Characters in train file: %d\n", t.train_chars)
+	}
+	return nil
+}
+
+// saveVocab writes the vocabulary as a version header followed by one
+// "character count" line per character. Characters are escaped as in the
+// word2vec format, so that every line can be split at its last space.
+// The header records train_chars, the number of characters of the
+// vocabulary in the training file, on which the learning rate schedule
+// and the subsampling are based, and the normalization, the unit of
+// training and the n-grams applied to the file.
+func (t *Trainer) saveVocab() error {
+	fmt.Fprintln(t.log, "SaveVocab")
+	f, err := os.Create(t.cfg.SaveVocabFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fo := bufio.NewWriter(f)
+	fmt.Fprintf(fo, "%s train_chars=%d", vocab_header, t.train_chars)
+	if t.cfg.Normalize != "" {
+		fmt.Fprintf(fo, " normalize=%s", t.cfg.Normalize)
+	}
+	if t.cfg.Unit == UnitGrapheme {
+		fmt.Fprintf(fo, " unit=%s", t.cfg.Unit)
+	}
+	if len(t.cfg.NGram) > 0 {
+		fmt.Fprintf(fo, " ngram=%s ngram_mode=%s", FormatNGram(t.cfg.NGram), t.ngramMode())
+	}
+	fmt.Fprintf(fo, "\n")
+	for i := 0; i < t.vocab_size; i++ {
+		fmt.Fprintf(fo, "%s %d\n", escapeToken(t.vocab[i].char), t.vocab[i].cn)
+	}
+	return fo.Flush()
+}
+
+// readVocab reads a vocabulary written by saveVocab from ReadVocabFile,
+// so that training starts without a counting pass over the training file.
+// Files without the version header are read in the old "%c %d" format.
+// train_chars is restored from the header, or from the counts for the
+// old format, as the learning rate schedule and the subsampling depend on
+// it.
+// The normalization and the unit recorded in the header are used when
+// Normalize and Unit are not set, and must match them otherwise. The
+// n-grams recorded in the header replace NGram and NGramMode, as they
+// are part of the vocabulary.
+func (t *Trainer) readVocab() error {
+	fmt.Fprintln(t.log, "ReadVocab")
+	var char string
+	var cn, train_chars int64
+	unit := UnitChar
+	f, err := os.Open(t.cfg.ReadVocabFile)
+	if err != nil {
+		return errors.New("Vocabulary file not found")
+	}
+	defer f.Close()
+	fin := bufio.NewReader(f)
+	header, _ := fin.Peek(len(vocab_header) + 1)
+	legacy := string(header) != vocab_header+"\n" && string(header) != vocab_header+" "
+	if !legacy {
+		line, _ := fin.ReadString('\n')
+		for _, field := range strings.Fields(line)[3:] {
+			if v, ok := strings.CutPrefix(field, "train_chars="); ok {
+				train_chars, _ = strconv.ParseInt(v, 10, 64)
+			}
+			if v, ok := strings.CutPrefix(field, "normalize="); ok {
+				if t.cfg.Normalize == "" {
+					t.cfg.Normalize = v
+				} else if t.cfg.Normalize != v {
+					return fmt.Errorf("%s: the vocabulary is normalized with %s, not %s", t.cfg.ReadVocabFile, v, t.cfg.Normalize)
+				}
+			}
+			if v, ok := strings.CutPrefix(field, "unit="); ok {
+				unit = v
+			}
+			if v, ok := strings.CutPrefix(field, "ngram="); ok {
+				if t.cfg.NGram, err = ParseNGram(v); err != nil {
+					return fmt.Errorf("%s: %v", t.cfg.ReadVocabFile, err)
+				}
+			}
+			if v, ok := strings.CutPrefix(field, "ngram_mode="); ok {
+				t.cfg.NGramMode = v
+			}
+		}
+	}
+	if t.cfg.Unit == "" {
+		t.cfg.Unit = unit
+	} else if t.cfg.Unit != unit {
+		return fmt.Errorf("%s: the vocabulary has the unit %s, not %s", t.cfg.ReadVocabFile, unit, t.cfg.Unit)
+	}
+	t.vocab_hash = map[string]int{}
+	t.vocab_size = 0
+	for {
+		if legacy {
+			char, cn, err = readLegacyVocabLine(fin)
+		} else {
+			char, cn, err = readVocabLine(fin)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", t.cfg.ReadVocabFile, err)
+		}
+		a := t.addCharToVocab(char)
+		t.vocab[a].cn = cn
+	}
+	t.sortVocab()
+	if train_chars > 0 {
+		t.train_chars = train_chars
+	}
+	if t.cfg.Debug > 0 {
+		fmt.Fprintf(t.log, "Vocab size: %d\n", t.vocab_size)
+		fmt.Fprintf(t.log, "Characters in train file: %d\n", t.train_chars)
+	}
+	return nil
+}
+
+// readVocabLine reads a "token count" line of the versioned format.
+func readVocabLine(fin *bufio.Reader) (string, int64, error) {
+	line, err := fin.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return "", 0, io.EOF
+	}
+	line = strings.TrimSuffix(line, "\n")
+	i := strings.LastIndexByte(line, ' ')
+	if i < 0 {
+		return "", 0, fmt.Errorf("invalid line %q", line)
+	}
+	char, ok := unescapeToken(line[:i])
+	if !ok {
+		return "", 0, fmt.Errorf("invalid character %q", line[:i])
+	}
+	cn, err := strconv.ParseInt(line[i+1:], 10, 64)
+	if err != nil {
+		return "", 0, err
+	}
+	return char, cn, nil
+}
+
+// readLegacyVocabLine reads a "%c %d" line. The character is taken by
+// position, so that whitespace characters are read back as well.
+func readLegacyVocabLine(fin *bufio.Reader) (string, int64, error) {
+	char, _, err := fin.ReadRune()
+	if err != nil {
+		return "", 0, err
+	}
+	line, err := fin.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", 0, err
+	}
+	cn, err := strconv.ParseInt(strings.TrimSpace(line), 10, 64)
+	if err != nil {
+		return "", 0, err
+	}
+	return string(char), cn, nil
+}
@@ -0,0 +1,92 @@
+package char2vec
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVocabRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	chars := []string{sentence_end, " ", "\n", "\t", "\r\n", "a", "あ", "U+2", "</s>", "👍🏽"}
+	tr := NewTrainer(Config{SaveVocabFile: filepath.Join(dir, "vocab"), Unit: UnitGrapheme, Log: io.Discard})
+	tr.vocab_size = 0
+	for a, char := range chars {
+		tr.vocab[tr.addCharToVocab(char)].cn = int64(100 - a)
+	}
+	tr.train_chars = 12345
+	if err := tr.saveVocab(); err != nil {
+		t.Fatal(err)
+	}
+	rd := NewTrainer(Config{ReadVocabFile: tr.cfg.SaveVocabFile, Unit: UnitGrapheme, Log: io.Discard})
+	if err := rd.readVocab(); err != nil {
+		t.Fatal(err)
+	}
+	if rd.vocab_size != len(chars) {
+		t.Fatalf("read %d characters, want %d", rd.vocab_size, len(chars))
+	}
+	if rd.train_chars != tr.train_chars {
+		t.Errorf("train_chars read as %d, want %d", rd.train_chars, tr.train_chars)
+	}
+	for a, char := range chars {
+		if rd.vocab[a].char != char || rd.vocab[a].cn != int64(100-a) {
+			t.Errorf("character %d read as %q %d, want %q %d", a, rd.vocab[a].char, rd.vocab[a].cn, char, 100-a)
+		}
+	}
+}
+
+func TestReadLegacyVocab(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vocab")
+	if err := os.WriteFile(path, []byte("\x00 0\n  39\nt 15\n\n 13\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rd := NewTrainer(Config{ReadVocabFile: path, Log: io.Discard})
+	if err := rd.readVocab(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{sentence_end, " ", "t", "\n"}
+	if rd.vocab_size != len(want) {
+		t.Fatalf("read %d characters, want %d", rd.vocab_size, len(want))
+	}
+	if rd.train_chars != 67 {
+		t.Errorf("train_chars counted as %d, want 67", rd.train_chars)
+	}
+	for a, char := range want {
+		if rd.vocab[a].char != char {
+			t.Errorf("character %d read as %q, want %q", a, rd.vocab[a].char, char)
+		}
+	}
+}
+
+func TestSortVocab(t *testing.T) {
+	tr := NewTrainer(Config{MinCount: 3, MaxVocab: 2, NGram: []int{2}, NGramMinCount: 2, Log: io.Discard})
+	tr.keep = map[string]bool{"k": true, "z": true}
+	counts := map[string]int64{"a": 10, "b": 8, "c": 6, "z": 5, "ab": 4, "d": 2, "k": 1, "bc": 1}
+	tr.vocab_size = 0
+	tr.addCharToVocab(sentence_end)
+	for _, char := range []string{"k", "a", "bc", "z", "d", "ab", "c", "b"} {
+		tr.vocab[tr.addCharToVocab(char)].cn = counts[char]
+	}
+	tr.sortVocab()
+	// The two most frequent characters, the n-grams reaching their own
+	// min_count whatever their rank, and the kept characters
+	want := []string{sentence_end, "a", "b", "z", "ab", "k"}
+	if tr.vocab_size != len(want) {
+		t.Fatalf("kept %d entries, want %d", tr.vocab_size, len(want))
+	}
+	for a, char := range want {
+		if tr.vocab[a].char != char || tr.searchVocab(char) != a {
+			t.Errorf("entry %d is %q, want %q", a, tr.vocab[a].char, char)
+		}
+	}
+	for _, char := range []string{"c", "d", "bc"} {
+		if tr.searchVocab(char) != -1 {
+			t.Errorf("%q kept", char)
+		}
+	}
+	// train_chars counts the kept characters, not the n-grams
+	if tr.train_chars != 24 {
+		t.Errorf("train_chars is %d, want 24", tr.train_chars)
+	}
+}